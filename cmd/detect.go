@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/taoky/ayano/pkg/fileiter"
+	"github.com/taoky/ayano/pkg/parser"
+	"github.com/taoky/ayano/pkg/util"
+)
+
+const (
+	detectSampleLines = 100
+	// Used when there's nothing to detect from (e.g. an empty log to follow)
+	fallbackParser = "nginx-json"
+)
+
+func sampleLines(filename string, n int) ([][]byte, error) {
+	if util.IsStream(filename) {
+		// Lines read would be put back for analysis
+		return util.PeekStreamLines(filename, n)
+	}
+	f, err := util.OpenFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines [][]byte
+	iter := fileiter.NewWithScanner(f)
+	for len(lines) < n {
+		line, err := iter.Next()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return lines, err
+		}
+		if line == nil {
+			break
+		}
+		lines = append(lines, append([]byte(nil), line...))
+	}
+	return lines, nil
+}
+
+// resolveParser replaces "auto" parser name with the one detected from the first log file.
+func resolveParser(w io.Writer, name *string, parserOpts []string, filenames []string) error {
+	if *name != parser.AutoParser {
+		return nil
+	}
+	opts, err := parser.ParseOptions(parserOpts)
+	if err != nil {
+		return err
+	}
+	var lines [][]byte
+	for _, filename := range filenames {
+		lines, err = sampleLines(filename, detectSampleLines)
+		if err != nil {
+			return fmt.Errorf("failed to read %s for parser detection: %w", filename, err)
+		}
+		if len(lines) > 0 {
+			break
+		}
+	}
+	if len(lines) == 0 {
+		fmt.Fprintf(w, "No lines to detect parser from, using %s\n", fallbackParser)
+		*name = fallbackParser
+		return nil
+	}
+	res, err := parser.Detect(lines, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Detected parser: %s (%.0f%% of %d sample lines parsed)\n", res.Name, res.Rate*100, len(lines))
+	*name = res.Name
+	return nil
+}
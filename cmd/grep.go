@@ -18,16 +18,20 @@ func grepCmd() *cobra.Command {
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
 
-		g, err := grep.New(config, cmd.OutOrStdout())
-		if err != nil {
-			return err
-		}
-		if g.IsEmpty() {
+		if config.IsEmpty() {
 			return errors.New("empty filter")
 		}
 
 		filenames := filenamesFromArgs(args)
 		fmt.Fprintln(cmd.ErrOrStderr(), "Using log files:", filenames)
+		if err := resolveParser(cmd.ErrOrStderr(), &config.Parser, config.ParserOpts, filenames); err != nil {
+			return err
+		}
+
+		g, err := grep.New(config, cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
 		for _, filename := range filenames {
 			err = g.GrepFile(filename)
 			if err != nil {
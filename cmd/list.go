@@ -55,6 +55,10 @@ func listParsersCmd() *cobra.Command {
 
 		table.Header("Name", "Description")
 
+		if err := table.Append([]string{parser.AutoParser, "Detect from the first lines of log (default)"}); err != nil {
+			return err
+		}
+
 		parsers := parser.All()
 		slices.SortFunc(parsers, func(a, b parser.ParserMeta) int {
 			return strings.Compare(a.Name, b.Name)
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/taoky/ayano/pkg/analyze"
+)
+
+func mergeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "merge [host=]snapshot...",
+		Short: "Merge analyzer snapshots (from --snapshot) of several hosts, and show top N",
+		Args:  cobra.MinimumNArgs(1),
+	}
+	config := analyze.DefaultConfig()
+	var dirs bool
+	flags := cmd.Flags()
+	flags.BoolVarP(&config.Absolute, "absolute", "a", config.Absolute, "Show absolute time for each item")
+	flags.IntVar(&config.Approx, "approx", config.Approx, "Merge approximately, keeping about this many top prefixes (for snapshots from --approx)")
+	flags.BoolVar(&dirs, "dir", false, "Show statistics for each first-level directory instead (requires snapshots from dir-analyze)")
+	flags.BoolVarP(&config.Group, "group", "g", config.Group, "Try to group CIDRs")
+	flags.StringVarP(&config.Filter.Server, "server", "s", config.Filter.Server, "Server to show (host, host/server, or server for snapshots with server field)")
+	flags.VarP(&config.SortBy, "sort-by", "S", "Sort result by (size|requests|rate)")
+	flags.IntVarP(&config.TopN, "top", "n", config.TopN, "Number of top items to show")
+	flags.Var(&config.Output, "output-format", "Output format (table|json|ndjson|csv)")
+	flags.BoolVar(&config.Total, "total", config.Total, "Show an additional \"Total\" row")
+	flags.BoolVar(&config.Truncate, "truncate", config.Truncate, "Truncate long URLs from output")
+	flags.IntVar(&config.Truncate2, "truncate-to", config.Truncate2, "Truncate URLs to given length, overrides --truncate")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		config.NoNetstat = true
+		// Logs are not parsed, and any valid parser would do
+		config.Parser = fallbackParser
+		if dirs {
+			config.DirAnalyze = true
+		} else {
+			config.Analyze = true
+		}
+
+		analyzer, err := analyze.NewAnalyzer(config)
+		if err != nil {
+			return fmt.Errorf("failed to create analyzer: %w", err)
+		}
+		for _, arg := range args {
+			if err := mergeSnapshot(analyzer, arg); err != nil {
+				return fmt.Errorf("failed to load %s: %w", arg, err)
+			}
+		}
+		if dirs {
+			analyzer.DirAnalyze(nil, config.SortBy)
+		} else {
+			analyzer.PrintTopValues(nil, config.SortBy, "")
+		}
+		return nil
+	}
+	return cmd
+}
+
+func mergeSnapshot(analyzer *analyze.Analyzer, arg string) error {
+	var host string
+	filename := arg
+	if _, err := os.Stat(arg); err != nil {
+		var ok bool
+		host, filename, ok = strings.Cut(arg, "=")
+		if !ok {
+			return err
+		}
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return analyzer.ReadHostSnapshot(f, host)
+}
@@ -22,6 +22,7 @@ func RootCmd() *cobra.Command {
 		dirAnalyzeCmd(),
 		grepCmd(),
 		listCmd(),
+		mergeCmd(),
 	)
 	return rootCmd
 }
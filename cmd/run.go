@@ -3,6 +3,8 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"runtime"
@@ -12,6 +14,7 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/taoky/ayano/pkg/analyze"
 	"github.com/taoky/ayano/pkg/fileiter"
+	"github.com/taoky/ayano/pkg/server"
 	"github.com/taoky/ayano/pkg/systemd"
 	"github.com/taoky/ayano/pkg/tui"
 	"github.com/taoky/ayano/pkg/util"
@@ -26,6 +29,36 @@ func filenamesFromArgs(args []string) []string {
 	return args
 }
 
+// orderLogFiles sorts rotated files of the same log oldest-first,
+// optionally adding rotated files of given logs.
+func orderLogFiles(filenames []string, rotated bool) ([]string, error) {
+	if rotated {
+		var expanded []string
+		seen := make(map[string]bool)
+		for _, filename := range filenames {
+			siblings := []string{filename}
+			if !util.IsStream(filename) {
+				var err error
+				siblings, err = util.RotatedSiblings(filename)
+				if err != nil {
+					return nil, err
+				}
+				if len(siblings) == 0 {
+					return nil, fmt.Errorf("no log files found for %s", filename)
+				}
+			}
+			for _, s := range siblings {
+				if !seen[s] {
+					seen[s] = true
+					expanded = append(expanded, s)
+				}
+			}
+		}
+		filenames = expanded
+	}
+	return util.SortRotated(filenames), nil
+}
+
 func runWithConfig(cmd *cobra.Command, args []string, config analyze.AnalyzerConfig) error {
 	// Sanily check
 	if config.Analyze && config.Daemon {
@@ -33,13 +66,29 @@ func runWithConfig(cmd *cobra.Command, args []string, config analyze.AnalyzerCon
 	}
 
 	filenames := filenamesFromArgs(args)
+	if config.Analyze || config.DirAnalyze {
+		var err error
+		filenames, err = orderLogFiles(filenames, config.Rotated)
+		if err != nil {
+			return err
+		}
+	}
 	fmt.Fprintln(cmd.ErrOrStderr(), "Using log files:", filenames)
 	cmd.SilenceUsage = true
 
+	if err := resolveParser(cmd.ErrOrStderr(), &config.Parser, config.ParserOpts, filenames); err != nil {
+		return err
+	}
+
 	analyzer, err := analyze.NewAnalyzer(config)
 	if err != nil {
 		return fmt.Errorf("failed to create analyzer: %w", err)
 	}
+	if config.Snapshot != "" {
+		if err := analyzer.LoadSnapshot(config.Snapshot); err != nil {
+			return fmt.Errorf("failed to load snapshot: %w", err)
+		}
+	}
 
 	// setup SIGHUP to reopen log file
 	c := make(chan os.Signal, 1)
@@ -48,18 +97,16 @@ func runWithConfig(cmd *cobra.Command, args []string, config analyze.AnalyzerCon
 		for range c {
 			systemd.MustNotifyReloading()
 			analyzer.OpenLogFile()
+			if err := analyzer.ReloadRules(); err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "failed to reload rules, keeping old ones:", err)
+			}
 			// Let GC close the old file
 			runtime.GC()
 			systemd.MustNotifyReady()
 		}
 	}()
 	analyzeFn := func() {
-		for _, filename := range filenames {
-			err = analyzer.AnalyzeFile(filename)
-			if err != nil {
-				break
-			}
-		}
+		err = analyzer.AnalyzeFiles(filenames)
 	}
 	if config.DirAnalyze {
 		if config.CpuProfile != "" {
@@ -71,7 +118,7 @@ func runWithConfig(cmd *cobra.Command, args []string, config analyze.AnalyzerCon
 		if config.MemProfile != "" {
 			util.MemProfile(config.MemProfile, "allocs")
 		}
-		return err
+		return errors.Join(err, analyzer.SaveState())
 	} else if config.Analyze {
 		if config.CpuProfile != "" {
 			util.RunCPUProfile(config.CpuProfile, analyzeFn)
@@ -79,10 +126,15 @@ func runWithConfig(cmd *cobra.Command, args []string, config analyze.AnalyzerCon
 			analyzeFn()
 		}
 		analyzer.PrintTopValues(nil, config.SortBy, "")
+		if config.Report != "" {
+			if reportErr := analyzer.WriteReport(config.Report, config.SortBy); reportErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to write report: %w", reportErr))
+			}
+		}
 		if config.MemProfile != "" {
 			util.MemProfile(config.MemProfile, "allocs")
 		}
-		return err
+		return errors.Join(err, analyzer.SaveState())
 	} else {
 		// Tail mode
 		var iters []fileiter.Iterator
@@ -94,6 +146,39 @@ func runWithConfig(cmd *cobra.Command, args []string, config analyze.AnalyzerCon
 			iters = append(iters, iter)
 		}
 
+		if config.Listen != "" {
+			l, err := server.Listen(config.Listen)
+			if err != nil {
+				return fmt.Errorf("failed to listen: %w", err)
+			}
+			defer l.Close()
+			go func() {
+				if err := http.Serve(l, server.New(analyzer)); err != nil && !errors.Is(err, net.ErrClosed) {
+					fmt.Fprintln(cmd.ErrOrStderr(), "HTTP server error:", err)
+				}
+			}()
+		}
+
+		if config.StateFile != "" && config.StateSave > 0 {
+			go analyzer.SaveCheckpointsEvery(config.StateSave)
+		}
+		if config.Snapshot != "" && config.SnapSave > 0 {
+			go analyzer.SaveSnapshotEvery(config.SnapSave)
+		}
+		if config.StateFile != "" || config.Snapshot != "" {
+			// Save state before exiting
+			term := make(chan os.Signal, 1)
+			signal.Notify(term, syscall.SIGTERM, syscall.SIGINT)
+			go func() {
+				<-term
+				if err := analyzer.SaveState(); err != nil {
+					fmt.Fprintln(cmd.ErrOrStderr(), "failed to save state:", err)
+					os.Exit(1)
+				}
+				os.Exit(0)
+			}()
+		}
+
 		if config.Daemon {
 			if err := systemd.NotifyReady(); err != nil {
 				return fmt.Errorf("failed to notify systemd: %w", err)
@@ -103,10 +188,15 @@ func runWithConfig(cmd *cobra.Command, args []string, config analyze.AnalyzerCon
 		}
 
 		if len(iters) == 1 {
-			return analyzer.RunLoop(iters[0])
+			err = analyzer.RunLoop(iters[0])
 		} else {
-			return analyzer.RunLoopWithMultipleIterators(iters)
+			err = analyzer.RunLoopWithMultipleIterators(iters)
+		}
+		// Only reached when all inputs are streams, and they have ended
+		if err == nil && !config.Daemon {
+			analyzer.PrintTopValues(nil, config.SortBy, "")
 		}
+		return errors.Join(err, analyzer.SaveState())
 	}
 }
 
@@ -125,6 +215,10 @@ func normalizeAnalyzeFlags(f *pflag.FlagSet, name string) pflag.NormalizedName {
 		name = "cpuprof"
 	case "memprofile":
 		name = "memprof"
+	case "since":
+		name = "time-from"
+	case "until":
+		name = "time-to"
 	}
 	return pflag.NormalizedName(name)
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/netip"
 	"os"
@@ -24,6 +25,9 @@ import (
 	"github.com/taoky/ayano/pkg/fileiter"
 	"github.com/taoky/ayano/pkg/grep"
 	"github.com/taoky/ayano/pkg/parser"
+	"github.com/taoky/ayano/pkg/rules"
+	"github.com/taoky/ayano/pkg/sketch"
+	"github.com/taoky/ayano/pkg/timeseek"
 	"github.com/taoky/ayano/pkg/util"
 )
 
@@ -37,6 +41,52 @@ type DirectoryTotalStats struct {
 	IPCount       map[netip.Prefix]struct{}
 	LastURLUpdate time.Time
 	LastURLAccess time.Time
+
+	// Used instead of IPCount in approximate mode
+	IPSketch *sketch.HyperLogLog
+}
+
+func (d *DirectoryTotalStats) addIP(prefix netip.Prefix) {
+	if d.IPSketch != nil {
+		d.IPSketch.Add(prefixHash(prefix))
+		return
+	}
+	if d.IPCount == nil {
+		d.IPCount = make(map[netip.Prefix]struct{})
+	}
+	d.IPCount[prefix] = struct{}{}
+}
+
+// IPs returns number of distinct prefixes, and whether it's estimated.
+func (d *DirectoryTotalStats) IPs() (int, bool) {
+	if d.IPSketch != nil {
+		return int(d.IPSketch.Estimate()), true
+	}
+	return len(d.IPCount), false
+}
+
+func (d *DirectoryTotalStats) MergeWith(other *DirectoryTotalStats) {
+	d.Size += other.Size
+	d.Requests += other.Requests
+	if other.IPSketch != nil && d.IPSketch == nil {
+		d.IPSketch = new(sketch.HyperLogLog)
+		for prefix := range d.IPCount {
+			d.IPSketch.Add(prefixHash(prefix))
+		}
+		d.IPCount = nil
+	}
+	if other.IPSketch != nil {
+		d.IPSketch.Merge(other.IPSketch)
+	}
+	for prefix := range other.IPCount {
+		d.addIP(prefix)
+	}
+	if other.LastURLUpdate.After(d.LastURLUpdate) {
+		d.LastURLUpdate = other.LastURLUpdate
+	}
+	if other.LastURLAccess.After(d.LastURLAccess) {
+		d.LastURLAccess = other.LastURLAccess
+	}
 }
 
 type DirectoryStats struct {
@@ -64,6 +114,12 @@ type IPStats struct {
 
 	// User-agent storage
 	UAStore map[UAKeyType]struct{}
+	// Used instead of UAStore in approximate mode
+	UASketch *sketch.HyperLogLog
+
+	// Approximate mode only: how much Size and Requests might be overestimated
+	SizeErr     uint64
+	RequestsErr uint64
 }
 
 func (i IPStats) UpdateWith(item parser.LogItem, dirStats bool) IPStats {
@@ -97,6 +153,10 @@ func (i IPStats) UpdateWith(item parser.LogItem, dirStats bool) IPStats {
 			i.LastURLAccess = item.Time
 		}
 	}
+	if i.UASketch != nil {
+		i.UASketch.AddString(item.Useragent)
+		return i
+	}
 	if i.UAStore == nil {
 		i.UAStore = make(map[UAKeyType]struct{})
 	}
@@ -104,23 +164,81 @@ func (i IPStats) UpdateWith(item parser.LogItem, dirStats bool) IPStats {
 	return i
 }
 
+// withUASketch moves user-agents in UAStore to UASketch.
+func (i IPStats) withUASketch() IPStats {
+	if i.UASketch != nil {
+		return i
+	}
+	i.UASketch = new(sketch.HyperLogLog)
+	for k := range i.UAStore {
+		i.UASketch.AddString(k.Value())
+	}
+	i.UAStore = nil
+	return i
+}
+
+// UserAgents returns number of distinct user-agents, and whether it's estimated.
+func (i IPStats) UserAgents() (int, bool) {
+	if i.UASketch != nil {
+		return int(i.UASketch.Estimate()), true
+	}
+	return len(i.UAStore), false
+}
+
 func (i IPStats) MergeWith(other IPStats) IPStats {
 	i.Size += other.Size
 	i.Requests += other.Requests
+	i.SizeErr += other.SizeErr
+	i.RequestsErr += other.RequestsErr
 	if i.LastURL == other.LastURL {
-		if other.LastURLAccess.After(i.LastURLAccess) {
-			i.LastURLAccess = other.LastURLAccess
-		}
-		if other.LastURLUpdate.Before(i.LastURLUpdate) {
+		switch {
+		case other.LastURLUpdate.After(i.LastURLAccess):
+			// Visited again later
 			i.LastURLUpdate = other.LastURLUpdate
+			i.LastURLAccess = other.LastURLAccess
+		case i.LastURLUpdate.After(other.LastURLAccess):
+			// Visited again later in i, keep it
+		default:
+			if other.LastURLAccess.After(i.LastURLAccess) {
+				i.LastURLAccess = other.LastURLAccess
+			}
+			if other.LastURLUpdate.Before(i.LastURLUpdate) {
+				i.LastURLUpdate = other.LastURLUpdate
+			}
 		}
 	} else if other.LastURLUpdate.After(i.LastURLUpdate) {
 		i.LastURL = other.LastURL
 		i.LastURLUpdate = other.LastURLUpdate
 		i.LastURLAccess = other.LastURLAccess
 	}
-	for k := range other.UAStore {
-		i.UAStore[k] = struct{}{}
+	if len(other.DirStats) > 0 && i.DirStats == nil {
+		i.DirStats = make(map[string]*DirectoryStats)
+	}
+	for dir, stats := range other.DirStats {
+		if s, ok := i.DirStats[dir]; ok {
+			s.Size += stats.Size
+			s.Requests += stats.Requests
+		} else {
+			i.DirStats[dir] = &DirectoryStats{
+				Size:     stats.Size,
+				Requests: stats.Requests,
+			}
+		}
+	}
+	if other.UASketch != nil {
+		i = i.withUASketch()
+		i.UASketch.Merge(other.UASketch)
+	} else if i.UASketch != nil {
+		for k := range other.UAStore {
+			i.UASketch.AddString(k.Value())
+		}
+	} else {
+		if len(other.UAStore) > 0 && i.UAStore == nil {
+			i.UAStore = make(map[UAKeyType]struct{})
+		}
+		for k := range other.UAStore {
+			i.UAStore[k] = struct{}{}
+		}
 	}
 	return i
 }
@@ -141,25 +259,71 @@ type Analyzer struct {
 	logParser parser.Parser
 	logger    *log.Logger
 	bar       *progressbar.ProgressBar
+
+	// Latest time of log lines
+	latestTime time.Time
+
+	// Used only when Config.Window or Config.HalfLife is set
+	recent *recentStats
+
+	// Used only when Config.Approx is set
+	approx *approxStats
+
+	// Used only when Config.TracksRate()
+	rates map[StatKey]rateStats
+
+	// Used only when Config.Rules is set
+	rules *rules.Engine
+
+	// Used only when Config.Listen is set
+	metrics *metrics
+
+	// Used only when Config.Report is set
+	hourly map[hourKey]counter
+
+	// Used only when Config.StateFile is set
+	checkpoints Checkpoints
+	// Positions after lines handled, protected by stateMu
+	positions Checkpoints
+	// Held for reading while a line from a checkpointed file is handled,
+	// and for writing while saving state, so that positions saved match stats
+	stateMu sync.RWMutex
 }
 
 type AnalyzerConfig struct {
 	Absolute   bool
+	Approx     int
+	ExtDecomp  bool
 	Group      bool
+	HalfLife   time.Duration
+	Jobs       int
+	Listen     string
 	LogOutput  string
 	NoNetstat  bool
+	Output     OutputFormat
 	Parser     string
+	ParserOpts []string
 	PrefixV4   int
 	PrefixV6   int
 	PrintDelta util.SizeFlag
+	Record     RecordFormat
 	RefreshSec int
+	Rotated    bool
 	RepeatWarn time.Duration
+	Report     string
+	Rules      string
+	Snapshot   string
+	SnapSave   time.Duration
 	SortBy     SortByFlag
+	StateFile  string
+	StateSave  time.Duration
 	TopN       int
 	Total      bool
+	TrackDirs  bool
 	Truncate   bool
 	Truncate2  int
 	Whole      bool
+	Window     time.Duration
 	Filter     grep.Filter
 
 	Analyze    bool
@@ -172,14 +336,21 @@ type AnalyzerConfig struct {
 
 func (c *AnalyzerConfig) InstallFlags(flags *pflag.FlagSet, cmdname string) {
 	flags.BoolVarP(&c.Absolute, "absolute", "a", c.Absolute, "Show absolute time for each item")
+	flags.IntVar(&c.Approx, "approx", c.Approx, "Track only about this many top prefixes approximately, with bounded memory (0 to track all exactly)")
+	flags.BoolVar(&c.ExtDecomp, "external-decompress", c.ExtDecomp, "Decompress logs with external commands (gzip, xz, etc.), which might be faster")
 	flags.StringVarP(&c.LogOutput, "outlog", "o", c.LogOutput, "Change log output file")
 	flags.BoolVarP(&c.NoNetstat, "no-netstat", "", c.NoNetstat, "Do not detect active connections")
+	if cmdname == "analyze" || cmdname == "dir-analyze" {
+		// Others show tables interactively
+		flags.Var(&c.Output, "output-format", "Output format (table|json|ndjson|csv)")
+	}
 	flags.StringVarP(&c.Parser, "parser", "p", c.Parser, "Log parser (see \"ayano list parsers\")")
+	flags.StringArrayVar(&c.ParserOpts, "parser-opt", c.ParserOpts, "Parser option in key=value form (can be specified multiple times)")
 	flags.IntVar(&c.PrefixV4, "prefixv4", c.PrefixV4, "Group IPv4 by prefix")
 	flags.IntVar(&c.PrefixV6, "prefixv6", c.PrefixV6, "Group IPv6 by prefix")
 	flags.DurationVar(&c.RepeatWarn, "repeat-warn", c.RepeatWarn, "Highlight repeated URL visits longer than duration")
 	flags.IntVarP(&c.RefreshSec, "refresh", "r", c.RefreshSec, "Refresh interval in seconds")
-	flags.VarP(&c.SortBy, "sort-by", "S", "Sort result by (size|requests)")
+	flags.VarP(&c.SortBy, "sort-by", "S", "Sort result by (size|requests|rate)")
 	flags.IntVarP(&c.TopN, "top", "n", c.TopN, "Number of top items to show")
 	flags.BoolVar(&c.Total, "total", c.Total, "Show an additional \"Total\" row")
 	flags.BoolVar(&c.Truncate, "truncate", c.Truncate, "Truncate long URLs from output")
@@ -187,6 +358,15 @@ func (c *AnalyzerConfig) InstallFlags(flags *pflag.FlagSet, cmdname string) {
 
 	c.Filter.InstallFlags(flags)
 
+	flags.StringVar(&c.Snapshot, "snapshot", c.Snapshot, "Load analyzer state from this file at start (if exists), and save to it on exit")
+	if cmdname == "run" || cmdname == "daemon" {
+		flags.DurationVar(&c.Window, "window", c.Window, "Only count traffic in this sliding time window (like 10m)")
+		flags.DurationVar(&c.HalfLife, "half-life", c.HalfLife, "Decay traffic counted exponentially with this half-life (like 10m)")
+		flags.DurationVar(&c.SnapSave, "snapshot-interval", c.SnapSave, "Also save snapshot at this interval (0 means only on exit)")
+		flags.StringVar(&c.Listen, "listen", c.Listen, "Serve metrics and query API over HTTP on this address (host:port, or unix:/path/to/socket)")
+		flags.BoolVar(&c.TrackDirs, "track-dirs", c.TrackDirs, "Also keep directory stats (of each client prefix too) for query API, using more memory")
+	}
+
 	flags.StringVar(&c.CpuProfile, "cpuprof", c.CpuProfile, "Write CPU profiling information")
 	flags.StringVar(&c.MemProfile, "memprof", c.MemProfile, "Write memory profiling information")
 
@@ -194,36 +374,72 @@ func (c *AnalyzerConfig) InstallFlags(flags *pflag.FlagSet, cmdname string) {
 		c.Whole = true
 		flags.BoolVarP(&c.Group, "group", "g", c.Group, "Try to group CIDRs")
 		flags.BoolVarP(new(bool), "whole", "w", false, "(This flag is implied in analyze mode)")
+		flags.StringVar(&c.Report, "report", c.Report, "Also write an HTML report (with directories and hourly traffic) to this file")
 	} else {
 		flags.BoolVarP(&c.Whole, "whole", "w", c.Whole, "Analyze whole log file and then tail it")
 	}
 
+	if cmdname == "analyze" || cmdname == "dir-analyze" {
+		flags.IntVarP(&c.Jobs, "jobs", "j", c.Jobs, "Number of parallel workers for parsing (each keeps its own statistics until merged, multiplying memory usage)")
+		flags.BoolVar(&c.Rotated, "rotated", c.Rotated, "Also analyze rotated files of given logs (like access.log.1, access.log-20240101.gz)")
+	}
+
 	if cmdname == "daemon" {
 		flags.Var(&c.PrintDelta, "print-delta", "Size interval for printing lines")
+		flags.Var(&c.Record, "record-format", "Format of lines printed (text|json)")
+		flags.StringVar(&c.Rules, "rules", c.Rules, "YAML file of rules to report clients with, instead of --print-delta")
+		flags.StringVar(&c.StateFile, "state-file", c.StateFile, "File to save positions of log files, to resume from after restart")
+		flags.DurationVar(&c.StateSave, "state-interval", c.StateSave, "Interval to save state file (0 means only on exit)")
 	}
 }
 
 func (c *AnalyzerConfig) UseLock() bool {
-	return !c.Analyze && !c.Daemon
+	if c.Daemon {
+		// Snapshot might be saved, or metrics served concurrently
+		return c.Snapshot != "" || c.Listen != ""
+	}
+	return !c.Analyze
 }
 
 func DefaultConfig() AnalyzerConfig {
 	filter := grep.Filter{}
 	filter.Threshold = util.SizeFlag(10e6)
 	return AnalyzerConfig{
-		Parser:     "nginx-json",
+		Jobs:       1,
+		Output:     OutputTable,
+		Record:     RecordText,
+		Parser:     parser.AutoParser,
 		PrefixV4:   24,
 		PrefixV6:   48,
 		PrintDelta: util.SizeFlag(1e9),
 		RefreshSec: 5,
 		SortBy:     SortBySize,
+		StateSave:  10 * time.Second,
 		Filter:     filter,
 		TopN:       10,
 	}
 }
 
+// newParser creates a parser with its own state, as configured in c.
+func newParser(c AnalyzerConfig) (parser.Parser, error) {
+	parserOpts, err := parser.ParseOptions(c.ParserOpts)
+	if err != nil {
+		return nil, err
+	}
+	return parser.GetParserWithOptions(c.Parser, parserOpts)
+}
+
+// newProgressBar creates progress bar of analyzer, which is not shown in daemon mode.
+// Replaced in tests, as bars keep rendering in background.
+var newProgressBar = func(silent bool) *progressbar.ProgressBar {
+	if silent {
+		return progressbar.DefaultSilent(-1, "analyzing")
+	}
+	return progressbar.Default(-1, "analyzing")
+}
+
 func NewAnalyzer(c AnalyzerConfig) (*Analyzer, error) {
-	logParser, err := parser.GetParser(c.Parser)
+	logParser, err := newParser(c)
 	if err != nil {
 		return nil, err
 	}
@@ -231,48 +447,124 @@ func NewAnalyzer(c AnalyzerConfig) (*Analyzer, error) {
 	if c.Analyze {
 		c.Whole = true
 	}
+	if c.Window > 0 && c.HalfLife > 0 {
+		return nil, errors.New("--window and --half-life are mutually exclusive")
+	}
+	if c.Window < 0 || c.HalfLife < 0 {
+		return nil, errors.New("--window and --half-life must be positive")
+	}
+	if c.Approx < 0 {
+		return nil, errors.New("--approx must not be negative")
+	}
+	if c.Approx > 0 && (c.Window > 0 || c.HalfLife > 0) {
+		return nil, errors.New("--approx could not be used with --window or --half-life")
+	}
 
 	logger := log.New(os.Stdout, "", log.LstdFlags)
 	if c.Analyze {
 		logger.SetFlags(log.Flags() &^ (log.Ldate | log.Ltime))
 	}
 
-	var bar *progressbar.ProgressBar
-	if !c.Daemon {
-		bar = progressbar.Default(-1, "analyzing")
-	} else {
-		bar = progressbar.DefaultSilent(-1, "analyzing")
-	}
-
 	a := &Analyzer{
 		Config:    c,
 		stats:     make(map[StatKey]IPStats),
 		logParser: logParser,
 		logger:    logger,
-		bar:       bar,
+		bar:       newProgressBar(c.Daemon),
 	}
 	err = a.OpenLogFile()
 	if err != nil {
 		return nil, fmt.Errorf("open log file error: %w", err)
 	}
-	if c.DirAnalyze {
+	if c.DirAnalyze || c.Report != "" || c.TrackDirs {
 		a.dirStats = make(map[string]*DirectoryTotalStats)
 	}
+	if c.Report != "" {
+		a.hourly = make(map[hourKey]counter)
+	}
+	if c.Window > 0 || c.HalfLife > 0 {
+		a.recent = newRecentStats(c.Window, c.HalfLife)
+	}
+	if c.Approx > 0 {
+		a.approx = newApproxStats(c.Approx)
+	}
+	if c.TracksRate() {
+		a.rates = make(map[StatKey]rateStats)
+	}
+	if c.Listen != "" {
+		a.metrics = newMetrics()
+	}
+	if c.Rules != "" {
+		r, err := rules.Load(c.Rules)
+		if err != nil {
+			return nil, err
+		}
+		a.rules = rules.NewEngine(r)
+	}
+	if c.StateFile != "" {
+		a.checkpoints, err = LoadCheckpoints(c.StateFile)
+		if err != nil {
+			return nil, err
+		}
+		a.positions = make(Checkpoints)
+	}
 	return a, nil
 }
 
+// ReloadRules reads rules file again, and resets counters of rules.
+func (a *Analyzer) ReloadRules() error {
+	if a.rules == nil {
+		return nil
+	}
+	r, err := rules.Load(a.Config.Rules)
+	if err != nil {
+		return err
+	}
+	a.rules.Replace(r)
+	return nil
+}
+
+// lineAt is a line, and position after it if it's from a checkpointed file.
+type lineAt struct {
+	line []byte
+	key  string
+	pos  fileiter.Position
+}
+
+func nextLine(iter fileiter.Iterator) (lineAt, error) {
+	line, err := iter.Next()
+	l := lineAt{line: line}
+	if ci, ok := iter.(*checkpointedIterator); ok && line != nil {
+		l.key = ci.key
+		l.pos = ci.Tell()
+	}
+	return l, err
+}
+
+// handleLineAt handles a line, and updates position of its file atomically for saving state.
+func (a *Analyzer) handleLineAt(l lineAt) error {
+	if l.key == "" {
+		return a.handleLine(l.line)
+	}
+	a.stateMu.RLock()
+	defer a.stateMu.RUnlock()
+	// Only one goroutine handles lines, so no other lock is needed for writing
+	a.positions[l.key] = l.pos
+	return a.handleLine(l.line)
+}
+
 func (a *Analyzer) RunLoop(iter fileiter.Iterator) error {
 	a.bar.Reset()
 	defer a.bar.Finish()
 	for {
-		line, err := iter.Next()
+		l, err := nextLine(iter)
 		if err != nil {
 			return err
 		}
-		if line == nil {
+		if l.line == nil {
 			break
 		}
-		if err := a.handleLine(line); err != nil {
+		if err := a.handleLineAt(l); err != nil {
 			a.logger.Printf("analyze error: %v", err)
 		}
 	}
@@ -284,7 +576,7 @@ func (a *Analyzer) RunLoopWithMultipleIterators(iters []fileiter.Iterator) error
 	defer a.bar.Finish()
 
 	var wg sync.WaitGroup
-	linesChan := make(chan []byte, 2*len(iters))
+	linesChan := make(chan lineAt, 2*len(iters))
 
 	var errorMu sync.Mutex
 	var collectedErrors []error
@@ -294,17 +586,17 @@ func (a *Analyzer) RunLoopWithMultipleIterators(iters []fileiter.Iterator) error
 		go func() {
 			defer wg.Done()
 			for {
-				line, err := iter.Next()
+				l, err := nextLine(iter)
 				if err != nil {
 					errorMu.Lock()
 					collectedErrors = append(collectedErrors, err)
 					errorMu.Unlock()
 					return
 				}
-				if line == nil {
+				if l.line == nil {
 					return
 				}
-				linesChan <- line
+				linesChan <- l
 			}
 		}()
 	}
@@ -315,7 +607,7 @@ func (a *Analyzer) RunLoopWithMultipleIterators(iters []fileiter.Iterator) error
 	}()
 
 	for result := range linesChan {
-		if err := a.handleLine(result); err != nil {
+		if err := a.handleLineAt(result); err != nil {
 			a.logger.Printf("analyze error: %v", err)
 		}
 	}
@@ -327,13 +619,24 @@ func (a *Analyzer) RunLoopWithMultipleIterators(iters []fileiter.Iterator) error
 	return nil
 }
 
+func (a *Analyzer) openFile(filename string) (io.ReadCloser, error) {
+	if a.Config.ExtDecomp {
+		return util.OpenFileWithCommand(filename)
+	}
+	return util.OpenFile(filename)
+}
+
 func (a *Analyzer) AnalyzeFile(filename string) error {
-	f, err := util.OpenFile(filename)
+	if a.outsideTimeRange(filename) {
+		a.logger.Printf("skipping %s: outside given time range", filename)
+		return nil
+	}
+	f, err := timeseek.OpenFrom(filename, a.openFile, a.logParser, a.Config.Filter.TimeFrom)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	return a.RunLoop(fileiter.NewWithScanner(f))
+	return a.RunLoop(timeseek.Until(fileiter.NewWithScanner(f), a.logParser, a.Config.Filter.TimeTo))
 }
 
 func (a *Analyzer) TailFile(filename string) error {
@@ -346,8 +649,15 @@ func (a *Analyzer) TailFile(filename string) error {
 
 func (a *Analyzer) handleLine(line []byte) error {
 	a.bar.Add64(1)
+	return a.processLine(line)
+}
+
+// processLine is handleLine without updating progress bar
+func (a *Analyzer) processLine(line []byte) error {
+	a.metrics.addLine()
 	logItem, err := a.logParser.Parse(line)
 	if err != nil {
+		a.metrics.addParseError()
 		return fmt.Errorf("parse error: %w\ngot line: %q", err, line)
 	}
 	return a.handleLogItem(logItem)
@@ -355,16 +665,25 @@ func (a *Analyzer) handleLine(line []byte) error {
 
 func (a *Analyzer) handleLogItem(logItem parser.LogItem) error {
 	if logItem.Discard {
+		a.metrics.addFiltered()
 		return nil
 	}
 
 	// Filter
+	// Requests below threshold are still counted by rules, or request-counting rules
+	// would never match with the default threshold
+	smallOnly := false
 	if err := a.Config.Filter.Match(logItem); err != nil {
-		return nil
+		a.metrics.addFiltered()
+		if !errors.Is(err, grep.ErrSizeTooSmall) || a.rules == nil {
+			return nil
+		}
+		smallOnly = true
 	}
 
 	clientip, err := netip.ParseAddr(logItem.Client)
 	if err != nil {
+		a.metrics.addParseError()
 		return fmt.Errorf("parse ip error: %w", err)
 	}
 	clientPrefix := a.IPPrefix(clientip)
@@ -374,8 +693,44 @@ func (a *Analyzer) handleLogItem(logItem parser.LogItem) error {
 		defer a.mu.Unlock()
 	}
 
+	if smallOnly {
+		a.observeRules(clientPrefix, logItem)
+		return nil
+	}
+
+	a.metrics.addServer(logItem.Server, logItem.Size)
+	a.metrics.observeTime(logItem.Time)
+
+	if a.recent != nil {
+		if logItem.Time.IsZero() {
+			logItem.Time = time.Now()
+		}
+		a.advanceRecent(logItem.Time)
+		if a.recent.isStale(logItem.Time) {
+			return nil
+		}
+	}
+
+	if logItem.Time.After(a.latestTime) {
+		a.latestTime = logItem.Time
+	}
+
 	updateStats := func(key StatKey) {
-		a.stats[key] = a.stats[key].UpdateWith(logItem, a.Config.DirAnalyze)
+		stats, ok := a.stats[key]
+		if !ok && a.approx != nil {
+			stats = a.admitApprox(key, logItem.Time)
+		}
+		stats = stats.UpdateWith(logItem, a.Config.DirAnalyze || a.Config.TrackDirs)
+		a.stats[key] = stats
+		if a.rates != nil {
+			a.rates[key] = a.rates[key].update(logItem.Time, logItem.Size, a.rateInterval())
+		}
+		if a.recent != nil {
+			a.recent.add(key, logItem.Size, logItem.Time)
+		}
+		if a.approx != nil {
+			a.approx.add(key, stats.Size, logItem.Size)
+		}
 	}
 
 	if a.Config.Analyze || a.Config.Daemon {
@@ -390,7 +745,9 @@ func (a *Analyzer) handleLogItem(logItem parser.LogItem) error {
 		}
 	}
 
-	if a.Config.Daemon {
+	if a.rules != nil {
+		a.observeRules(clientPrefix, logItem)
+	} else if a.Config.Daemon {
 		ipStats := a.stats[StatKey{a.Config.Filter.Server, clientPrefix}]
 		delta := ipStats.Size - ipStats.LastSize
 		if ipStats.LastSize == 0 {
@@ -398,49 +755,55 @@ func (a *Analyzer) handleLogItem(logItem parser.LogItem) error {
 		}
 		printTimes := delta / uint64(a.Config.PrintDelta)
 		for range printTimes {
-			a.logger.Printf("%s %s %s %s",
-				clientPrefix.String(),
-				humanize.IBytes(ipStats.Size),
-				ipStats.FirstSeen.Format(TimeFormat),
-				logItem.URL)
+			a.printDeltaRecord(clientPrefix, ipStats, logItem)
 		}
 		ipStats.LastSize += printTimes * uint64(a.Config.PrintDelta)
 		// Just update [StatKey{a.Config.Filter.Server, clientPrefix}] here, as the config would not be updated runtime now
 		a.stats[StatKey{a.Config.Filter.Server, clientPrefix}] = ipStats
 	}
 
-	if a.Config.DirAnalyze {
+	if a.hourly != nil {
+		a.addHourly(logItem)
+	}
+
+	if a.dirStats != nil {
 		dir := GetFirstDirectory(logItem.URL)
-		if stats, ok := a.dirStats[dir]; ok {
-			stats.Size += logItem.Size
-			stats.Requests++
-			if stats.IPCount == nil {
-				stats.IPCount = make(map[netip.Prefix]struct{})
-			}
-			stats.IPCount[clientPrefix] = struct{}{}
-			if logItem.Time.After(stats.LastURLAccess) {
-				stats.LastURLUpdate = logItem.Time
-				stats.LastURLAccess = logItem.Time
-			}
-		} else {
-			ipCount := make(map[netip.Prefix]struct{})
-			ipCount[clientPrefix] = struct{}{}
-			a.dirStats[dir] = &DirectoryTotalStats{
-				Size:          logItem.Size,
-				Requests:      1,
-				IPCount:       ipCount,
+		stats, ok := a.dirStats[dir]
+		if !ok {
+			stats = &DirectoryTotalStats{
 				LastURLUpdate: logItem.Time,
 				LastURLAccess: logItem.Time,
 			}
+			if a.approx != nil {
+				stats.IPSketch = new(sketch.HyperLogLog)
+			}
+			a.dirStats[dir] = stats
+		}
+		stats.Size += logItem.Size
+		stats.Requests++
+		stats.addIP(clientPrefix)
+		if logItem.Time.After(stats.LastURLAccess) {
+			stats.LastURLUpdate = logItem.Time
+			stats.LastURLAccess = logItem.Time
 		}
 	}
 
 	return nil
 }
 
+// observeRules counts log item in rules, and reports matches.
+func (a *Analyzer) observeRules(clientPrefix netip.Prefix, logItem parser.LogItem) {
+	if logItem.Time.IsZero() {
+		logItem.Time = time.Now()
+	}
+	for _, m := range a.rules.Observe(clientPrefix, logItem) {
+		a.printRuleRecord(m, a.stats[StatKey{a.Config.Filter.Server, clientPrefix}], logItem)
+	}
+}
+
 func filterSockTabEntry(s *netstat.SockTabEntry) bool {
 	switch s.LocalAddr.Port {
-	case 80, 443, 873:
+	case 21, 80, 443, 873:
 	default:
 		return false
 	}
@@ -484,38 +847,45 @@ func (a *Analyzer) SortedKeys(sortBy SortByFlag, serverFilter string) []StatKey
 		}
 		keys = append(keys, s)
 	}
-	sortFunc := GetSortFunc(sortBy, a.stats)
+	sortFunc := GetSortFunc(sortBy, a)
 	if sortFunc != nil {
 		slices.SortFunc(keys, sortFunc)
 	}
 	return keys
 }
 
+// dirRecords returns top directories of directory stats.
+func (a *Analyzer) dirRecords(sortBy SortByFlag) []DirRecord {
+	records := make([]DirRecord, 0, len(a.dirStats))
+	for dir, stats := range a.dirStats {
+		r := DirRecord{
+			Directory:  dir,
+			Bytes:      stats.Size,
+			Requests:   stats.Requests,
+			AvgBytes:   stats.Size / stats.Requests,
+			LastAccess: stats.LastURLAccess,
+		}
+		r.IPs, r.IPsEstimated = stats.IPs()
+		records = append(records, r)
+	}
+	sortDirRecords(records, sortBy)
+	return records
+}
+
 func (a *Analyzer) DirAnalyze(displayRecord map[netip.Prefix]time.Time, sortBy SortByFlag) {
 	if a.Config.UseLock() {
 		a.mu.Lock()
 		defer a.mu.Unlock()
 	}
 
-	type dirEntry struct {
-		dir   string
-		stats *DirectoryTotalStats
-	}
-
-	dirs := make([]dirEntry, 0, len(a.dirStats))
-	for dir, stats := range a.dirStats {
-		dirs = append(dirs, dirEntry{dir, stats})
+	records := limit(a.dirRecords(sortBy), a.Config.TopN)
+	if a.Config.Output != OutputTable {
+		if err := writeRecords(a.logger.Writer(), a.Config.Output, dirCSVHeader, "directories", records, nil); err != nil {
+			a.logger.Printf("failed to write output: %v", err)
+		}
+		return
 	}
 
-	if sortBy == SortBySize {
-		slices.SortFunc(dirs, func(a, b dirEntry) int {
-			return int(b.stats.Size - a.stats.Size)
-		})
-	} else if sortBy == SortByRequests {
-		slices.SortFunc(dirs, func(a, b dirEntry) int {
-			return int(b.stats.Requests - a.stats.Requests)
-		})
-	}
 	tableBuf := new(bytes.Buffer)
 
 	alignments := tw.Alignment{
@@ -546,30 +916,20 @@ func (a *Analyzer) DirAnalyze(displayRecord map[netip.Prefix]time.Time, sortBy S
 
 	table.Header("Directory", "Size", "Requests", "Avg Size", "IPs", "Last Access")
 
-	// Show top N directories
-	top := a.Config.TopN
-	if len(dirs) < top || top == 0 {
-		top = len(dirs)
-	}
-
 	// Add row data
 	now := time.Now()
-	for i := 0; i < top; i++ {
-		dir := dirs[i]
-		stats := dir.stats
-		avgSize := stats.Size / uint64(stats.Requests)
-
-		lastAccess := HumanizeAgo(now.Sub(stats.LastURLAccess))
+	for _, r := range records {
+		lastAccess := HumanizeAgo(now.Sub(r.LastAccess))
 		if a.Config.Absolute {
-			lastAccess = stats.LastURLAccess.Format(TimeFormat)
+			lastAccess = r.LastAccess.Format(TimeFormat)
 		}
 
 		row := []string{
-			dir.dir,
-			humanize.IBytes(stats.Size),
-			strconv.FormatUint(stats.Requests, 10),
-			humanize.IBytes(avgSize),
-			strconv.Itoa(len(stats.IPCount)),
+			r.Directory,
+			humanize.IBytes(r.Bytes),
+			strconv.FormatUint(r.Requests, 10),
+			humanize.IBytes(r.AvgBytes),
+			formatCount(r.IPs, r.IPsEstimated),
 			lastAccess,
 		}
 
@@ -600,6 +960,10 @@ func (a *Analyzer) PrintTopValues(displayRecord map[netip.Prefix]time.Time, sort
 		defer a.mu.Unlock()
 	}
 
+	if a.recent != nil {
+		a.advanceRecentToNow()
+	}
+
 	keys := a.SortedKeys(sortBy, serverFilter)
 
 	// print top N
@@ -631,9 +995,13 @@ func (a *Analyzer) PrintTopValues(displayRecord map[netip.Prefix]time.Time, sort
 				mergedPrefix := netip.PrefixFrom(key.Prefix.Addr(), key.Prefix.Bits()-1).Masked()
 				newKey := StatKey{key.Server, mergedPrefix}
 
+				newRate := a.rates[key].mergeWith(a.rates[adjacentKey])
+				a.deleteStats(key)
+				a.deleteStats(adjacentKey)
 				a.stats[newKey] = newStat
-				delete(a.stats, key)
-				delete(a.stats, adjacentKey)
+				if a.rates != nil {
+					a.rates[newKey] = newRate
+				}
 
 				groupedKeys[newKey] = struct{}{}
 				delete(groupedKeys, key)
@@ -647,6 +1015,9 @@ func (a *Analyzer) PrintTopValues(displayRecord map[netip.Prefix]time.Time, sort
 				_, ok = groupedKeys[adjacentKey]
 			}
 		}
+		if a.approx != nil {
+			a.approx.rebuild(a.stats)
+		}
 		keys = a.SortedKeys(sortBy, serverFilter)
 		if len(keys) < top {
 			top = len(keys)
@@ -658,6 +1029,46 @@ func (a *Analyzer) PrintTopValues(displayRecord map[netip.Prefix]time.Time, sort
 		for _, stat := range a.stats {
 			totalStats = totalStats.MergeWith(stat)
 		}
+		if a.approx != nil {
+			// Including untracked prefixes
+			totalStats.Size = a.approx.sizes.Total()
+			totalStats.Requests = a.approx.requests.Total()
+		}
+	}
+
+	if a.Config.Output != OutputTable {
+		records := make([]TopRecord, 0, top+1)
+		for _, key := range keys[:top] {
+			records = append(records, a.newTopRecord(key, a.stats[key], activeConn))
+		}
+		extra := make(map[string]any)
+		if a.Config.Total {
+			total := TopRecord{
+				CIDR:     "Total",
+				Bytes:    totalStats.Size,
+				Requests: totalStats.Requests,
+			}
+			if totalStats.Requests > 0 {
+				total.AvgBytes = totalStats.Size / totalStats.Requests
+			}
+			total.UserAgents, total.UserAgentsEstimated = totalStats.UserAgents()
+			if !a.Config.NoNetstat {
+				conn := len(activeConn)
+				total.Conn = &conn
+			}
+			if a.Config.Output == OutputJSON {
+				extra["total"] = total
+			} else {
+				records = append(records, total)
+			}
+		}
+		if a.approx != nil {
+			extra["approx"] = a.approxRecord()
+		}
+		if err := writeRecords(a.logger.Writer(), a.Config.Output, topCSVHeader, "items", records, extra); err != nil {
+			a.logger.Printf("failed to write output: %v", err)
+		}
+		return
 	}
 
 	tableBuf := new(bytes.Buffer)
@@ -672,8 +1083,21 @@ func (a *Analyzer) PrintTopValues(displayRecord map[netip.Prefix]time.Time, sort
 		tw.AlignRight,
 		tw.AlignRight,
 		tw.AlignRight,
+		tw.AlignRight,
+		tw.AlignRight,
+	}
+	headers := []string{"CIDR", "Conn", "Bytes", "Reqs", "Avg", "URL", "URL Since", "URL Last", "UA", "Rate", "Peak"}
+	// Rate is already the average over active span in analyze mode
+	showAvgRate := !a.Config.Analyze
+	const avgRateColIdx = 10
+	if showAvgRate {
+		alignments = slices.Insert(alignments, avgRateColIdx, tw.AlignRight)
+		headers = slices.Insert(headers, avgRateColIdx, "Avg Rate")
+	}
+	if a.approx != nil {
+		alignments = append(alignments, tw.AlignRight)
+		headers = append(headers, "Error")
 	}
-	headers := []string{"CIDR", "Conn", "Bytes", "Reqs", "Avg", "URL", "URL Since", "URL Last", "UA"}
 	if a.Config.NoNetstat {
 		alignments = append(alignments[:1], alignments[2:]...)
 		headers = append(headers[:1], headers[2:]...)
@@ -747,7 +1171,6 @@ func (a *Analyzer) PrintTopValues(displayRecord map[netip.Prefix]time.Time, sort
 		total := ipStats.Size
 		reqTotal := ipStats.Requests
 		last := ipStats.LastURL
-		agents := len(ipStats.UAStore)
 		if a.Config.Truncate2 > 0 {
 			last = TruncateURLPathLen(last, a.Config.Truncate2)
 		} else if a.Config.Truncate {
@@ -774,7 +1197,14 @@ func (a *Analyzer) PrintTopValues(displayRecord map[netip.Prefix]time.Time, sort
 
 		row := []string{
 			key.Prefix.String(), "", humanize.IBytes(total), strconv.FormatUint(reqTotal, 10),
-			humanize.IBytes(average), last, lastUpdateTime, lastAccessTime, strconv.Itoa(agents),
+			humanize.IBytes(average), last, lastUpdateTime, lastAccessTime, formatCount(ipStats.UserAgents()),
+			humanizeRate(a.rate(key)), humanizeRate(a.peakRate(key)),
+		}
+		if showAvgRate {
+			row = slices.Insert(row, avgRateColIdx, humanizeRate(a.avgRate(key)))
+		}
+		if a.approx != nil {
+			row = append(row, humanize.IBytes(ipStats.SizeErr))
 		}
 
 		if !a.Config.NoNetstat {
@@ -800,10 +1230,15 @@ func (a *Analyzer) PrintTopValues(displayRecord map[netip.Prefix]time.Time, sort
 		if reqTotal > 0 {
 			average = total / uint64(reqTotal)
 		}
-		agents := len(totalStats.UAStore)
 		row := []string{
 			"Total", "", humanize.IBytes(total), strconv.FormatUint(reqTotal, 10),
-			humanize.IBytes(average), "", "", "", strconv.Itoa(agents),
+			humanize.IBytes(average), "", "", "", formatCount(totalStats.UserAgents()), "", "",
+		}
+		if showAvgRate {
+			row = slices.Insert(row, avgRateColIdx, "")
+		}
+		if a.approx != nil {
+			row = append(row, "")
 		}
 		if !a.Config.NoNetstat {
 			row[1] = strconv.FormatInt(int64(len(activeConn)), 10)
@@ -819,6 +1254,9 @@ func (a *Analyzer) PrintTopValues(displayRecord map[netip.Prefix]time.Time, sort
 			a.logger.Writer().Write([]byte{'\n'})
 		}
 		a.logger.Writer().Write(tableBuf.Bytes())
+		if a.approx != nil {
+			a.logger.Writer().Write([]byte(a.approxSummary()))
+		}
 	}
 }
 
@@ -863,6 +1301,17 @@ func (a *Analyzer) PrintTotal() {
 		return int(j.value - i.value)
 	})
 
+	if a.Config.Output != OutputTable {
+		records := make([]ServerRecord, 0, len(totalSlice))
+		for _, kv := range totalSlice {
+			records = append(records, ServerRecord{kv.server, kv.value})
+		}
+		if err := writeRecords(a.logger.Writer(), a.Config.Output, serverCSVHeader, "servers", records, nil); err != nil {
+			a.logger.Printf("failed to write output: %v", err)
+		}
+		return
+	}
+
 	for _, kv := range totalSlice {
 		a.logger.Printf("%s: %s\n", kv.server, humanize.IBytes(kv.value))
 	}
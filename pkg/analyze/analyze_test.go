@@ -4,10 +4,39 @@ import (
 	"os"
 	"testing"
 
+	"github.com/schollz/progressbar/v3"
 	"github.com/taoky/ayano/pkg/grep"
 	"github.com/taoky/ayano/pkg/util"
 )
 
+func init() {
+	newProgressBar = func(bool) *progressbar.ProgressBar {
+		return progressbar.NewOptions64(-1, progressbar.OptionSetVisibility(false))
+	}
+}
+
+// newTestAnalyzer creates an analyzer counting all log items, with config changed by configure.
+func newTestAnalyzer(t *testing.T, configure func(c *AnalyzerConfig)) *Analyzer {
+	c := DefaultConfig()
+	c.NoNetstat = true
+	c.Parser = "nginx-combined"
+	c.Filter.Threshold = 0
+	configure(&c)
+	a, err := NewAnalyzer(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func analyzeConfig(c *AnalyzerConfig) {
+	c.Analyze = true
+}
+
+func dirAnalyzeConfig(c *AnalyzerConfig) {
+	c.DirAnalyze = true
+}
+
 func benchmarkAnalyzeLoop(b *testing.B, parserStr string) {
 	// get logPath from env
 	logPath := os.Getenv("LOG_PATH")
@@ -0,0 +1,231 @@
+package analyze
+
+import (
+	"cmp"
+	"container/heap"
+	"fmt"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/taoky/ayano/pkg/sketch"
+)
+
+// approxStats bounds a.stats to capacity entries with Space-Saving algorithm:
+// when full, the entry with the smallest size is evicted, and a new entry starts
+// from an upper bound of its untracked past, recorded as its error.
+// Count-Min sketches give tighter bounds for most keys than the evicted size.
+type approxStats struct {
+	capacity int
+	heap     approxHeap
+
+	sizes    *sketch.CountMin
+	requests *sketch.CountMin
+	// Distinct keys seen, including evicted ones
+	keys sketch.HyperLogLog
+
+	// Upper bounds of size and requests of any key not in a.stats
+	untrackedSize     uint64
+	untrackedRequests uint64
+}
+
+func newApproxStats(capacity int) *approxStats {
+	return &approxStats{
+		capacity: capacity,
+		heap:     approxHeap{index: make(map[StatKey]int)},
+		sizes:    sketch.NewCountMin(),
+		requests: sketch.NewCountMin(),
+	}
+}
+
+type approxEntry struct {
+	key  StatKey
+	size uint64
+}
+
+// approxHeap is a min-heap of tracked keys by size.
+type approxHeap struct {
+	entries []approxEntry
+	index   map[StatKey]int
+}
+
+func (h approxHeap) Len() int { return len(h.entries) }
+
+func (h approxHeap) Less(i, j int) bool { return h.entries[i].size < h.entries[j].size }
+
+func (h approxHeap) Swap(i, j int) {
+	h.entries[i], h.entries[j] = h.entries[j], h.entries[i]
+	h.index[h.entries[i].key] = i
+	h.index[h.entries[j].key] = j
+}
+
+func (h *approxHeap) Push(x any) {
+	e := x.(approxEntry)
+	h.index[e.key] = len(h.entries)
+	h.entries = append(h.entries, e)
+}
+
+func (h *approxHeap) Pop() any {
+	e := h.entries[len(h.entries)-1]
+	h.entries = h.entries[:len(h.entries)-1]
+	delete(h.index, e.key)
+	return e
+}
+
+func hashPrefix(h *sketch.Hasher, prefix netip.Prefix) {
+	addr := prefix.Addr().As16()
+	h.Write(addr[:])
+	h.Write([]byte{byte(prefix.Bits())})
+}
+
+func hashKey(key StatKey) uint64 {
+	h := sketch.NewHasher()
+	hashPrefix(&h, key.Prefix)
+	h.WriteString(key.Server)
+	return h.Sum64()
+}
+
+func prefixHash(prefix netip.Prefix) uint64 {
+	h := sketch.NewHasher()
+	hashPrefix(&h, prefix)
+	return h.Sum64()
+}
+
+// admitApprox makes room for a new key, and returns its initial stats.
+func (a *Analyzer) admitApprox(key StatKey, t time.Time) IPStats {
+	x := a.approx
+	if x.heap.Len() >= x.capacity {
+		evicted := heap.Pop(&x.heap).(approxEntry).key
+		stats := a.stats[evicted]
+		x.untrackedSize = max(x.untrackedSize, stats.Size)
+		x.untrackedRequests = max(x.untrackedRequests, stats.Requests)
+		a.deleteStats(evicted)
+	}
+	h := hashKey(key)
+	x.keys.Add(h)
+	stats := IPStats{
+		UASketch:    new(sketch.HyperLogLog),
+		SizeErr:     min(x.untrackedSize, x.sizes.Estimate(h)),
+		RequestsErr: min(x.untrackedRequests, x.requests.Estimate(h)),
+	}
+	stats.Size = stats.SizeErr
+	stats.Requests = stats.RequestsErr
+	if a.Config.Daemon {
+		// Do not report just for the error
+		delta := max(uint64(a.Config.PrintDelta), 1)
+		stats.LastSize = stats.Size / delta * delta
+		stats.FirstSeen = t
+	}
+	heap.Push(&x.heap, approxEntry{key, stats.Size})
+	return stats
+}
+
+// add records a log item already counted in a.stats[key].
+func (x *approxStats) add(key StatKey, total uint64, size uint64) {
+	h := hashKey(key)
+	x.sizes.Add(h, size)
+	x.requests.Add(h, 1)
+	i := x.heap.index[key]
+	x.heap.entries[i].size = total
+	heap.Fix(&x.heap, i)
+}
+
+// rebuild makes heap consistent with stats, after they are changed in other ways.
+func (x *approxStats) rebuild(stats map[StatKey]IPStats) {
+	x.heap.entries = x.heap.entries[:0]
+	clear(x.heap.index)
+	for k, v := range stats {
+		x.heap.index[k] = len(x.heap.entries)
+		x.heap.entries = append(x.heap.entries, approxEntry{k, v.Size})
+	}
+	heap.Init(&x.heap)
+}
+
+// mergeStats merges stats from another analyzer or snapshot into a.
+// from is nil if stats are exact.
+func (a *Analyzer) mergeStats(stats map[StatKey]IPStats, from *approxStats) {
+	x := a.approx
+	if x == nil {
+		for k, v := range stats {
+			if s, ok := a.stats[k]; ok {
+				a.stats[k] = s.MergeWith(v)
+			} else {
+				a.stats[k] = v
+			}
+		}
+		return
+	}
+
+	// A key missing on one side might have up to the untracked bound there
+	if from != nil && (from.untrackedSize > 0 || from.untrackedRequests > 0) {
+		for k, v := range a.stats {
+			if _, ok := stats[k]; ok {
+				continue
+			}
+			h := hashKey(k)
+			size := min(from.untrackedSize, from.sizes.Estimate(h))
+			requests := min(from.untrackedRequests, from.requests.Estimate(h))
+			v.Size += size
+			v.SizeErr += size
+			v.Requests += requests
+			v.RequestsErr += requests
+			a.stats[k] = v
+		}
+	}
+	for k, v := range stats {
+		if s, ok := a.stats[k]; ok {
+			a.stats[k] = s.MergeWith(v)
+			continue
+		}
+		h := hashKey(k)
+		size := min(x.untrackedSize, x.sizes.Estimate(h))
+		requests := min(x.untrackedRequests, x.requests.Estimate(h))
+		v.Size += size
+		v.SizeErr += size
+		v.Requests += requests
+		v.RequestsErr += requests
+		a.stats[k] = v.withUASketch()
+	}
+
+	if from != nil {
+		x.untrackedSize += from.untrackedSize
+		x.untrackedRequests += from.untrackedRequests
+		x.sizes.Merge(from.sizes)
+		x.requests.Merge(from.requests)
+		x.keys.Merge(&from.keys)
+	} else {
+		for k, v := range stats {
+			h := hashKey(k)
+			x.sizes.Add(h, v.Size)
+			x.requests.Add(h, v.Requests)
+			x.keys.Add(h)
+		}
+	}
+
+	if len(a.stats) > x.capacity {
+		keys := make([]StatKey, 0, len(a.stats))
+		for k := range a.stats {
+			keys = append(keys, k)
+		}
+		slices.SortFunc(keys, func(l, r StatKey) int {
+			return cmp.Compare(a.stats[l].Size, a.stats[r].Size)
+		})
+		for _, k := range keys[:len(keys)-x.capacity] {
+			v := a.stats[k]
+			x.untrackedSize = max(x.untrackedSize, v.Size)
+			x.untrackedRequests = max(x.untrackedRequests, v.Requests)
+			a.deleteStats(k)
+		}
+	}
+	x.rebuild(a.stats)
+}
+
+// approxSummary describes accuracy of approximate results.
+func (a *Analyzer) approxSummary() string {
+	x := a.approx
+	return fmt.Sprintf("Approximate: tracking %d of about %d prefixes (±%.0f%%). "+
+		"Bytes may exceed actual values by up to Error. Untracked prefixes have at most %s and %d requests each.\n",
+		len(a.stats), max(x.keys.Estimate(), uint64(len(a.stats))), sketch.HLLRelativeError*100,
+		humanize.IBytes(x.untrackedSize), x.untrackedRequests)
+}
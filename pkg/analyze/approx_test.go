@@ -0,0 +1,109 @@
+package analyze
+
+import (
+	"bytes"
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taoky/ayano/pkg/parser"
+)
+
+func newApproxAnalyzer(t *testing.T, capacity int) *Analyzer {
+	return newTestAnalyzer(t, func(c *AnalyzerConfig) {
+		c.Analyze = true
+		c.Approx = capacity
+	})
+}
+
+// feedApprox sends a few heavy clients among many light ones, and returns true sizes.
+func feedApprox(t *testing.T, a *Analyzer, seed int) map[StatKey]uint64 {
+	truth := make(map[StatKey]uint64)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 20000 {
+		client := fmt.Sprintf("10.%d.%d.1", i%97, (i*7+seed)%251)
+		size := uint64(10 + i%13)
+		if i%10 == 0 {
+			client = fmt.Sprintf("192.168.%d.1", i%3)
+			size = 1000
+		}
+		if err := a.handleLogItem(parser.LogItem{
+			Client:    client,
+			Size:      size,
+			URL:       "/file",
+			Time:      now.Add(time.Duration(i) * time.Second),
+			Useragent: fmt.Sprintf("ua%d", i%7),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		truth[StatKey{Prefix: a.IPPrefix(netip.MustParseAddr(client))}] += size
+	}
+	return truth
+}
+
+func checkApproxBounds(as *assert.Assertions, a *Analyzer, truth map[StatKey]uint64) {
+	as.LessOrEqual(len(a.stats), a.Config.Approx)
+	as.Len(a.approx.heap.entries, len(a.stats))
+	for k, v := range truth {
+		stats, ok := a.stats[k]
+		if !ok {
+			as.LessOrEqual(v, a.approx.untrackedSize, "%v", k)
+			continue
+		}
+		as.GreaterOrEqual(stats.Size, v, "%v", k)
+		as.LessOrEqual(stats.Size-stats.SizeErr, v, "%v", k)
+	}
+}
+
+func TestApprox(t *testing.T) {
+	as := assert.New(t)
+	a := newApproxAnalyzer(t, 100)
+	truth := feedApprox(t, a, 0)
+	checkApproxBounds(as, a, truth)
+
+	keys := a.SortedKeys(SortBySize, "")
+	for i := range 3 {
+		key := StatKey{Prefix: netip.MustParsePrefix(fmt.Sprintf("192.168.%d.0/24", i))}
+		as.Contains(keys[:3], key)
+		as.Zero(a.stats[key].SizeErr)
+		agents, estimated := a.stats[key].UserAgents()
+		as.True(estimated)
+		as.Equal(7, agents)
+	}
+	as.InDelta(len(truth), a.approx.keys.Estimate(), float64(len(truth))/5)
+
+	b := newApproxAnalyzer(t, 100)
+	truthB := feedApprox(t, b, 1)
+	a.mergeStats(b.stats, b.approx)
+	for k, v := range truthB {
+		truth[k] += v
+	}
+	checkApproxBounds(as, a, truth)
+}
+
+func TestApproxSnapshot(t *testing.T) {
+	as := assert.New(t)
+	a := newApproxAnalyzer(t, 100)
+	truth := feedApprox(t, a, 0)
+
+	var buf bytes.Buffer
+	as.NoError(a.WriteSnapshot(&buf))
+	data := buf.Bytes()
+
+	b := newApproxAnalyzer(t, 100)
+	as.NoError(b.ReadSnapshot(bytes.NewReader(data)))
+	as.Equal(a.stats, b.stats)
+	as.Equal(a.approx.untrackedSize, b.approx.untrackedSize)
+	as.Equal(a.approx.sizes, b.approx.sizes)
+
+	// Exact snapshot into approximate analyzer
+	c := newTestAnalyzer(t, analyzeConfig)
+	feedApprox(t, c, 0)
+	buf.Reset()
+	as.NoError(c.WriteSnapshot(&buf))
+	d := newApproxAnalyzer(t, 100)
+	as.NoError(d.ReadSnapshot(&buf))
+	checkApproxBounds(as, d, truth)
+}
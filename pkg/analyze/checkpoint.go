@@ -0,0 +1,109 @@
+package analyze
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/taoky/ayano/pkg/fileiter"
+	"github.com/taoky/ayano/pkg/util"
+)
+
+// Checkpoints maps log filenames to positions of lines analyzed.
+type Checkpoints map[string]fileiter.Position
+
+func LoadCheckpoints(filename string) (Checkpoints, error) {
+	c := make(Checkpoints)
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %w", filename, err)
+	}
+	return c, nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it,
+// so filename is never left half-written.
+func writeFileAtomic(filename string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
+
+func (c Checkpoints) Save(filename string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, data)
+}
+
+// openFollower resumes following filename from checkpoint.
+// If the file has been rotated since then, the rotated one is finished first.
+func (a *Analyzer) openFollower(filename string, pos fileiter.Position) (*fileiter.Follower, error) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if fileiter.FilePosition(fi).SameFile(pos) {
+		return fileiter.NewFollower(filename, pos, "")
+	}
+	siblings, err := util.RotatedSiblings(filename)
+	if err != nil {
+		return nil, err
+	}
+	for _, sibling := range siblings {
+		fi, err := os.Stat(sibling)
+		if err == nil && fileiter.FilePosition(fi).SameFile(pos) {
+			a.logger.Printf("resuming from rotated %s", sibling)
+			return fileiter.NewFollower(filename, pos, sibling)
+		}
+	}
+	a.logger.Printf("rotated file of %s in checkpoint not found, starting from beginning", filename)
+	return fileiter.NewFollower(filename, fileiter.Position{}, "")
+}
+
+// currentCheckpoints returns positions after lines handled. stateMu must be held.
+func (a *Analyzer) currentCheckpoints() Checkpoints {
+	if a.positions == nil {
+		return nil
+	}
+	return maps.Clone(a.positions)
+}
+
+// SaveCheckpoints saves positions of all followed files to Config.StateFile.
+func (a *Analyzer) SaveCheckpoints() error {
+	a.stateMu.Lock()
+	c := a.currentCheckpoints()
+	a.stateMu.Unlock()
+	return c.Save(a.Config.StateFile)
+}
+
+// SaveCheckpointsEvery saves checkpoints periodically, and never returns. interval must be positive.
+func (a *Analyzer) SaveCheckpointsEvery(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := a.SaveCheckpoints(); err != nil {
+			a.logger.Printf("save checkpoints error: %v", err)
+		}
+	}
+}
@@ -1,16 +1,33 @@
 package analyze
 
 import (
+	"errors"
 	"io"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/nxadm/tail"
 	"github.com/taoky/ayano/pkg/fileiter"
+	"github.com/taoky/ayano/pkg/util"
 )
 
 const oneMiB = 1024 * 1024
 
 func (a *Analyzer) OpenTailIterator(filename string) (fileiter.Iterator, error) {
+	if util.IsStream(filename) {
+		// Streams are just read until EOF
+		f, err := util.OpenFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		return fileiter.NewWithScanner(f), nil
+	}
+
+	if a.Config.StateFile != "" {
+		return a.openCheckpointedIterator(filename)
+	}
+
 	var seekInfo *tail.SeekInfo
 	if a.Config.Whole {
 		seekInfo = &tail.SeekInfo{
@@ -55,3 +72,108 @@ func (a *Analyzer) OpenTailIterator(filename string) (fileiter.Iterator, error)
 	}
 	return fileiter.NewWithTail(t), nil
 }
+
+func (a *Analyzer) openCheckpointedIterator(filename string) (fileiter.Iterator, error) {
+	key, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	var fl *fileiter.Follower
+	if pos, ok := a.checkpoints[key]; ok {
+		fl, err = a.openFollower(filename, pos)
+	} else {
+		fl, err = a.openFollowerWithoutCheckpoint(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.stateMu.Lock()
+	a.positions[key] = fl.Tell()
+	a.stateMu.Unlock()
+	return &checkpointedIterator{Follower: fl, key: key}, nil
+}
+
+// checkpointedIterator is a follower, whose position is saved to state file.
+type checkpointedIterator struct {
+	*fileiter.Follower
+	// Absolute filename
+	key string
+}
+
+// openFollowerWithoutCheckpoint starts like nxadm/tail does in OpenTailIterator
+func (a *Analyzer) openFollowerWithoutCheckpoint(filename string) (*fileiter.Follower, error) {
+	fileInfo, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	pos := fileiter.FilePosition(fileInfo)
+	if !a.Config.Whole {
+		pos.Offset = max(0, fileInfo.Size()-oneMiB)
+	}
+	fl, err := fileiter.NewFollower(filename, pos, "")
+	if err != nil {
+		return nil, err
+	}
+	if pos.Offset > 0 {
+		// First line may be incomplete
+		if _, err := fl.Next(); err != nil {
+			return nil, err
+		}
+	}
+	return fl, nil
+}
+
+// Number of lines to look for the first timestamp in a file
+const firstTimeLines = 100
+
+// outsideTimeRange reports whether all lines of a file are outside filter's time range,
+// judging by time of its first line, and its mtime as time of its last line.
+func (a *Analyzer) outsideTimeRange(filename string) bool {
+	from, to := a.Config.Filter.TimeFrom, a.Config.Filter.TimeTo
+	if (from.IsZero() && to.IsZero()) || util.IsStream(filename) {
+		return false
+	}
+	if !from.IsZero() {
+		fileInfo, err := os.Stat(filename)
+		if err == nil && fileInfo.ModTime().Before(from) {
+			return true
+		}
+	}
+	if !to.IsZero() {
+		first, err := a.firstTime(filename)
+		if err == nil && first.After(to) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Analyzer) firstTime(filename string) (time.Time, error) {
+	// A fresh parser, so that state of stateful parsers (like CloudFront's #Fields)
+	// is not changed for the real pass
+	p, err := newParser(a.Config)
+	if err != nil {
+		return time.Time{}, err
+	}
+	f, err := a.openFile(filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+	iter := fileiter.NewWithScanner(f)
+	for range firstTimeLines {
+		line, err := iter.Next()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return time.Time{}, err
+		}
+		if line == nil {
+			break
+		}
+		item, err := p.Parse(line)
+		if err != nil || item.Discard || item.Time.IsZero() {
+			continue
+		}
+		return item.Time, nil
+	}
+	return time.Time{}, errors.New("no timestamp found")
+}
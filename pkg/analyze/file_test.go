@@ -0,0 +1,37 @@
+package analyze
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFirstTimeKeepsParserState(t *testing.T) {
+	as := assert.New(t)
+	c := DefaultConfig()
+	c.NoNetstat = true
+	c.Parser = "cloudfront"
+	a, err := NewAnalyzer(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "access.log")
+	content := "#Version: 1.0\n#Fields: c-ip date time cs-uri-stem sc-bytes\n2001:db8::1\t2019-12-04\t21:02:31\t/big.iso\t123456\n"
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	first, err := a.firstTime(filename)
+	as.NoError(err)
+	as.WithinDuration(time.Date(2019, 12, 4, 21, 2, 31, 0, time.UTC), first, 0)
+
+	// Shared parser still uses default fields
+	item, err := a.logParser.Parse([]byte("2019-12-04\t21:02:31\tLAX1\t392\t192.0.2.100\tGET\td111111abcdef8.cloudfront.net\t/index.html\t200"))
+	if as.NoError(err) {
+		as.Equal("192.0.2.100", item.Client)
+		as.EqualValues(392, item.Size)
+	}
+}
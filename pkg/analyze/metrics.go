@@ -0,0 +1,132 @@
+package analyze
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// metrics are counters exported in Prometheus format. Methods are no-op on nil.
+type metrics struct {
+	lines       atomic.Uint64
+	parseErrors atomic.Uint64
+	filtered    atomic.Uint64
+	// Unix nanoseconds of latest log time
+	latest atomic.Int64
+
+	// Protected by Analyzer.mu
+	servers map[string]counter
+}
+
+func newMetrics() *metrics {
+	return &metrics{servers: make(map[string]counter)}
+}
+
+func (m *metrics) addLine() {
+	if m != nil {
+		m.lines.Add(1)
+	}
+}
+
+func (m *metrics) addParseError() {
+	if m != nil {
+		m.parseErrors.Add(1)
+	}
+}
+
+func (m *metrics) addFiltered() {
+	if m != nil {
+		m.filtered.Add(1)
+	}
+}
+
+func (m *metrics) observeTime(t time.Time) {
+	if m == nil || t.IsZero() {
+		return
+	}
+	for {
+		old := m.latest.Load()
+		if t.UnixNano() <= old || m.latest.CompareAndSwap(old, t.UnixNano()) {
+			return
+		}
+	}
+}
+
+// addServer must be called with Analyzer.mu held
+func (m *metrics) addServer(server string, size uint64) {
+	if m == nil {
+		return
+	}
+	c := m.servers[server]
+	c.Size += size
+	c.Requests++
+	m.servers[server] = c
+}
+
+// promLabel escapes a Prometheus label value.
+var promLabel = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteMetrics writes metrics in Prometheus text format. Config.Listen must be set.
+func (a *Analyzer) WriteMetrics(w io.Writer) error {
+	m := a.metrics
+	if m == nil {
+		return fmt.Errorf("metrics are not enabled")
+	}
+	var b strings.Builder
+	metric := func(name, typ, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+
+	metric("ayano_lines_total", "counter", "Log lines read.")
+	fmt.Fprintf(&b, "ayano_lines_total %d\n", m.lines.Load())
+	metric("ayano_parse_errors_total", "counter", "Log lines failed to parse.")
+	fmt.Fprintf(&b, "ayano_parse_errors_total %d\n", m.parseErrors.Load())
+	metric("ayano_filtered_lines_total", "counter", "Log lines discarded by parser or filters.")
+	fmt.Fprintf(&b, "ayano_filtered_lines_total %d\n", m.filtered.Load())
+
+	if latest := m.latest.Load(); latest != 0 {
+		t := time.Unix(0, latest)
+		metric("ayano_last_log_timestamp_seconds", "gauge", "Latest time of log lines read.")
+		fmt.Fprintf(&b, "ayano_last_log_timestamp_seconds %d\n", t.Unix())
+		metric("ayano_tail_lag_seconds", "gauge", "Seconds between now and latest time of log lines read.")
+		fmt.Fprintf(&b, "ayano_tail_lag_seconds %.3f\n", time.Since(t).Seconds())
+	}
+
+	unlock := a.lockForQuery()
+	servers := make([]string, 0, len(m.servers))
+	for s := range m.servers {
+		servers = append(servers, s)
+	}
+	slices.Sort(servers)
+	metric("ayano_server_bytes_total", "counter", "Bytes of requests counted, by server.")
+	for _, s := range servers {
+		fmt.Fprintf(&b, "ayano_server_bytes_total{server=\"%s\"} %d\n", promLabel.Replace(s), m.servers[s].Size)
+	}
+	metric("ayano_server_requests_total", "counter", "Requests counted, by server.")
+	for _, s := range servers {
+		fmt.Fprintf(&b, "ayano_server_requests_total{server=\"%s\"} %d\n", promLabel.Replace(s), m.servers[s].Requests)
+	}
+
+	keys := a.SortedKeys(SortBySize, a.Config.Filter.Server)
+	metric("ayano_tracked_prefixes", "gauge", "Client prefixes with statistics in memory.")
+	fmt.Fprintf(&b, "ayano_tracked_prefixes %d\n", len(keys))
+
+	if a.Config.TopN > 0 && len(keys) > a.Config.TopN {
+		keys = keys[:a.Config.TopN]
+	}
+	metric("ayano_top_bytes", "gauge", "Bytes of top client prefixes by bytes.")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "ayano_top_bytes{cidr=\"%s\"} %d\n", k.Prefix, a.stats[k].Size)
+	}
+	metric("ayano_top_requests", "gauge", "Requests of top client prefixes by bytes.")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "ayano_top_requests{cidr=\"%s\"} %d\n", k.Prefix, a.stats[k].Requests)
+	}
+	unlock()
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,66 @@
+package analyze
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taoky/ayano/pkg/parser"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	as := assert.New(t)
+	a := newTestAnalyzer(t, func(c *AnalyzerConfig) {
+		c.Daemon = true
+		c.TopN = 1
+		c.Listen = "127.0.0.1:0"
+	})
+
+	for i, client := range []string{"10.0.0.1", "10.0.1.1", "10.0.1.2"} {
+		line := fmt.Sprintf(`%s - - [01/Jan/2024:00:00:0%d +0000] "GET /a.iso HTTP/1.1" 200 %d "-" "curl"`, client, i, 100*(i+1))
+		as.NoError(a.processLine([]byte(line)))
+	}
+	as.Error(a.processLine([]byte("garbage")))
+
+	var b strings.Builder
+	as.NoError(a.WriteMetrics(&b))
+	out := b.String()
+	as.Contains(out, "# TYPE ayano_lines_total counter\nayano_lines_total 4\n")
+	as.Contains(out, "ayano_parse_errors_total 1\n")
+	as.Contains(out, "ayano_filtered_lines_total 0\n")
+	as.Contains(out, "ayano_server_bytes_total{server=\"\"} 600\n")
+	as.Contains(out, "ayano_server_requests_total{server=\"\"} 3\n")
+	as.Contains(out, "ayano_tracked_prefixes 2\n")
+	as.Contains(out, "ayano_top_bytes{cidr=\"10.0.1.0/24\"} 500\n")
+	as.Contains(out, "ayano_top_requests{cidr=\"10.0.1.0/24\"} 2\n")
+	as.NotContains(out, "10.0.0.0/24")
+	as.Contains(out, "ayano_last_log_timestamp_seconds 1704067202\n")
+	as.Contains(out, "ayano_tail_lag_seconds ")
+
+	a = newTestAnalyzer(t, func(c *AnalyzerConfig) {
+		c.Daemon = true
+	})
+	as.Error(a.WriteMetrics(&b))
+}
+
+func TestWriteMetricsWindow(t *testing.T) {
+	as := assert.New(t)
+	a := newTestAnalyzer(t, func(c *AnalyzerConfig) {
+		c.Daemon = true
+		c.Listen = "127.0.0.1:0"
+		c.Window = time.Minute
+	})
+	as.NoError(a.handleLogItem(parser.LogItem{Client: "10.0.0.1", Size: 100, URL: "/a", Time: time.Now().Add(-5 * time.Minute)}))
+	var b strings.Builder
+	as.NoError(a.WriteMetrics(&b))
+	as.Contains(b.String(), "ayano_tracked_prefixes 1\n")
+
+	// Live log without lines for a while
+	a.recent.latest = time.Now()
+	b.Reset()
+	as.NoError(a.WriteMetrics(&b))
+	as.Contains(b.String(), "ayano_tracked_prefixes 0\n")
+	as.NotContains(b.String(), "10.0.0.0/24")
+}
@@ -0,0 +1,205 @@
+package analyze
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"time"
+)
+
+type OutputFormat string
+
+const (
+	OutputTable  OutputFormat = "table"
+	OutputJSON   OutputFormat = "json"
+	OutputNDJSON OutputFormat = "ndjson"
+	OutputCSV    OutputFormat = "csv"
+)
+
+func (o OutputFormat) String() string {
+	return string(o)
+}
+
+func (o *OutputFormat) Set(value string) error {
+	switch OutputFormat(value) {
+	case OutputTable, OutputJSON, OutputNDJSON, OutputCSV:
+		*o = OutputFormat(value)
+	default:
+		return fmt.Errorf("must be one of: %v", []OutputFormat{OutputTable, OutputJSON, OutputNDJSON, OutputCSV})
+	}
+	return nil
+}
+
+func (o OutputFormat) Type() string {
+	return "string"
+}
+
+// Machine-readable records have raw numbers and RFC 3339 times.
+
+// TopRecord is a row of the top table.
+type TopRecord struct {
+	Server              string    `json:"server,omitempty"`
+	CIDR                string    `json:"cidr"`
+	Conn                *int      `json:"conn,omitempty"`
+	Bytes               uint64    `json:"bytes"`
+	Requests            uint64    `json:"requests"`
+	AvgBytes            uint64    `json:"avg_bytes"`
+	LastURL             string    `json:"last_url,omitempty"`
+	LastURLSince        time.Time `json:"last_url_since,omitzero"`
+	LastURLAccess       time.Time `json:"last_url_access,omitzero"`
+	UserAgents          int       `json:"user_agents"`
+	UserAgentsEstimated bool      `json:"user_agents_estimated,omitempty"`
+	// Bytes per second in the last refresh interval, or the same as AvgRate in analyze mode
+	Rate     uint64 `json:"rate"`
+	AvgRate  uint64 `json:"avg_rate"`
+	PeakRate uint64 `json:"peak_rate"`
+	// Approximate mode only
+	BytesError uint64 `json:"bytes_error,omitempty"`
+}
+
+var topCSVHeader = []string{
+	"server", "cidr", "conn", "bytes", "requests", "avg_bytes", "last_url", "last_url_since", "last_url_access",
+	"user_agents", "user_agents_estimated", "rate", "avg_rate", "peak_rate", "bytes_error",
+}
+
+func (r TopRecord) csvRow() []string {
+	conn := ""
+	if r.Conn != nil {
+		conn = strconv.Itoa(*r.Conn)
+	}
+	return []string{
+		r.Server, r.CIDR, conn, strconv.FormatUint(r.Bytes, 10), strconv.FormatUint(r.Requests, 10),
+		strconv.FormatUint(r.AvgBytes, 10), r.LastURL, formatRFC3339(r.LastURLSince), formatRFC3339(r.LastURLAccess),
+		strconv.Itoa(r.UserAgents), strconv.FormatBool(r.UserAgentsEstimated),
+		strconv.FormatUint(r.Rate, 10), strconv.FormatUint(r.AvgRate, 10), strconv.FormatUint(r.PeakRate, 10),
+		strconv.FormatUint(r.BytesError, 10),
+	}
+}
+
+// DirRecord is a row of the directory table.
+type DirRecord struct {
+	Directory    string    `json:"directory"`
+	Bytes        uint64    `json:"bytes"`
+	Requests     uint64    `json:"requests"`
+	AvgBytes     uint64    `json:"avg_bytes"`
+	IPs          int       `json:"ips"`
+	IPsEstimated bool      `json:"ips_estimated,omitempty"`
+	LastAccess   time.Time `json:"last_access,omitzero"`
+}
+
+var dirCSVHeader = []string{"directory", "bytes", "requests", "avg_bytes", "ips", "last_access"}
+
+func (r DirRecord) csvRow() []string {
+	return []string{
+		r.Directory, strconv.FormatUint(r.Bytes, 10), strconv.FormatUint(r.Requests, 10),
+		strconv.FormatUint(r.AvgBytes, 10), strconv.Itoa(r.IPs), formatRFC3339(r.LastAccess),
+	}
+}
+
+// ServerRecord is total size of a server.
+type ServerRecord struct {
+	Server string `json:"server"`
+	Bytes  uint64 `json:"bytes"`
+}
+
+var serverCSVHeader = []string{"server", "bytes"}
+
+func (r ServerRecord) csvRow() []string {
+	return []string{r.Server, strconv.FormatUint(r.Bytes, 10)}
+}
+
+func formatRFC3339(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+type csvRecord interface {
+	csvRow() []string
+}
+
+// writeRecords writes records as a JSON document (with records under key,
+// and extra fields), a JSON object per line, or CSV with a header.
+func writeRecords[T csvRecord](w io.Writer, format OutputFormat, header []string, key string, records []T, extra map[string]any) error {
+	switch format {
+	case OutputJSON:
+		doc := map[string]any{
+			"time": time.Now().Format(time.RFC3339),
+			key:    records,
+		}
+		for k, v := range extra {
+			doc[k] = v
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case OutputNDJSON:
+		enc := json.NewEncoder(w)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case OutputCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for _, r := range records {
+			if err := cw.Write(r.csvRow()); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+func (a *Analyzer) newTopRecord(key StatKey, stats IPStats, activeConn map[netip.Prefix]int) TopRecord {
+	r := TopRecord{
+		Server:        key.Server,
+		CIDR:          key.Prefix.String(),
+		Bytes:         stats.Size,
+		Requests:      stats.Requests,
+		LastURL:       stats.LastURL,
+		LastURLSince:  stats.LastURLUpdate,
+		LastURLAccess: stats.LastURLAccess,
+		Rate:          uint64(a.rate(key)),
+		AvgRate:       uint64(a.avgRate(key)),
+		PeakRate:      uint64(a.peakRate(key)),
+		BytesError:    stats.SizeErr,
+	}
+	if stats.Requests > 0 {
+		r.AvgBytes = stats.Size / stats.Requests
+	}
+	r.UserAgents, r.UserAgentsEstimated = stats.UserAgents()
+	if !a.Config.NoNetstat {
+		conn := activeConn[key.Prefix]
+		r.Conn = &conn
+	}
+	return r
+}
+
+type approxRecord struct {
+	Tracked           int    `json:"tracked"`
+	Distinct          uint64 `json:"distinct"`
+	UntrackedBytes    uint64 `json:"untracked_bytes"`
+	UntrackedRequests uint64 `json:"untracked_requests"`
+}
+
+func (a *Analyzer) approxRecord() approxRecord {
+	x := a.approx
+	return approxRecord{
+		Tracked:           len(a.stats),
+		Distinct:          max(x.keys.Estimate(), uint64(len(a.stats))),
+		UntrackedBytes:    x.untrackedSize,
+		UntrackedRequests: x.untrackedRequests,
+	}
+}
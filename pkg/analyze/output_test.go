@@ -0,0 +1,71 @@
+package analyze
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taoky/ayano/pkg/parser"
+)
+
+func TestMachineOutput(t *testing.T) {
+	as := assert.New(t)
+	a := newTestAnalyzer(t, analyzeConfig)
+	a.Config.Total = true
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, client := range []string{"10.0.0.1", "10.0.0.2", "10.0.1.1"} {
+		as.NoError(a.handleLogItem(parser.LogItem{
+			Client:    client,
+			Size:      uint64(100 << i),
+			URL:       "/debian/pool/a.deb",
+			Time:      now.Add(time.Duration(i) * time.Minute),
+			Useragent: "apt",
+		}))
+	}
+	var buf bytes.Buffer
+	a.logger.SetOutput(&buf)
+
+	a.Config.Output = OutputJSON
+	a.PrintTopValues(nil, SortBySize, "")
+	var doc struct {
+		Items []TopRecord
+		Total TopRecord
+	}
+	as.NoError(json.Unmarshal(buf.Bytes(), &doc))
+	as.Len(doc.Items, 2)
+	as.Equal("10.0.1.0/24", doc.Items[0].CIDR)
+	as.Equal(uint64(300), doc.Items[1].Bytes)
+	as.Equal(uint64(2), doc.Items[1].Requests)
+	as.Equal(now.Add(time.Minute), doc.Items[1].LastURLAccess)
+	as.Equal(1, doc.Items[1].UserAgents)
+	as.Equal(uint64(700), doc.Total.Bytes)
+
+	buf.Reset()
+	a.Config.Output = OutputNDJSON
+	a.PrintTopValues(nil, SortBySize, "")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	as.Len(lines, 3)
+	as.Contains(lines[2], `"cidr":"Total"`)
+
+	buf.Reset()
+	a.Config.Output = OutputCSV
+	a.PrintTopValues(nil, SortBySize, "")
+	rows, err := csv.NewReader(&buf).ReadAll()
+	as.NoError(err)
+	as.Len(rows, 4)
+	as.Equal(topCSVHeader, rows[0])
+	as.Equal([]string{"", "10.0.0.0/24", "", "300", "2", "150", "/debian/pool/a.deb",
+		"2024-01-01T00:00:00Z", "2024-01-01T00:01:00Z", "1", "false", "5", "5", "40", "0"}, rows[2])
+
+	d := newTestAnalyzer(t, dirAnalyzeConfig)
+	as.NoError(d.handleLogItem(parser.LogItem{Client: "10.0.0.1", Size: 100, URL: "/debian/a", Time: now}))
+	buf.Reset()
+	d.logger.SetOutput(&buf)
+	d.Config.Output = OutputCSV
+	d.DirAnalyze(nil, SortBySize)
+	as.Equal("directory,bytes,requests,avg_bytes,ips,last_access\n/debian,100,1,100,1,2024-01-01T00:00:00Z\n", buf.String())
+}
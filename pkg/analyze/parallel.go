@@ -0,0 +1,308 @@
+package analyze
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/taoky/ayano/pkg/fileiter"
+	"github.com/taoky/ayano/pkg/parser"
+	"github.com/taoky/ayano/pkg/timeseek"
+	"github.com/taoky/ayano/pkg/util"
+)
+
+// Plain files smaller than twice of this are not split into chunks
+var minChunkSize int64 = 64 * oneMiB
+
+// Workers update shared progress bar every progressBatch lines to avoid lock contention
+const progressBatch = 4096
+
+type workUnit struct {
+	filename string
+	// Byte range [start, end) of a plain file, and end < 0 means until EOF.
+	// (0, -1) means the whole file, which might be compressed or a stream.
+	start, end int64
+}
+
+func wholeFileUnit(filename string) workUnit {
+	return workUnit{filename: filename, end: -1}
+}
+
+func (u workUnit) isWhole() bool {
+	return u.start == 0 && u.end < 0
+}
+
+// AnalyzeFiles analyzes files in order, or with Config.Jobs workers in parallel,
+// each having its own stats, which are merged at the end.
+func (a *Analyzer) AnalyzeFiles(filenames []string) error {
+	if a.Config.Jobs <= 1 {
+		for _, filename := range filenames {
+			if err := a.AnalyzeFile(filename); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	units, err := a.planUnits(filenames)
+	if err != nil {
+		return err
+	}
+	workers := make([]*Analyzer, min(a.Config.Jobs, len(units)))
+	for i := range workers {
+		workers[i], err = a.newWorker()
+		if err != nil {
+			return err
+		}
+	}
+
+	a.bar.Reset()
+	defer a.bar.Finish()
+
+	var wg sync.WaitGroup
+	unitChan := make(chan workUnit)
+
+	var errorMu sync.Mutex
+	var collectedErrors []error
+
+	for _, w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range unitChan {
+				if err := w.analyzeUnit(u, a.bar); err != nil {
+					errorMu.Lock()
+					collectedErrors = append(collectedErrors, fmt.Errorf("%s: %w", u.filename, err))
+					errorMu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, u := range units {
+		unitChan <- u
+	}
+	close(unitChan)
+	wg.Wait()
+
+	for _, w := range workers {
+		a.mergeFrom(w)
+	}
+	return errors.Join(collectedErrors...)
+}
+
+// planUnits splits large plain files into line-aligned chunks.
+func (a *Analyzer) planUnits(filenames []string) ([]workUnit, error) {
+	var units []workUnit
+	for _, filename := range filenames {
+		if a.outsideTimeRange(filename) {
+			a.logger.Printf("skipping %s: outside given time range", filename)
+			continue
+		}
+		if parser.IsStateful(a.logParser) {
+			// Could not start parsing from the middle
+			units = append(units, wholeFileUnit(filename))
+			continue
+		}
+		chunks, err := a.splitFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		units = append(units, chunks...)
+	}
+	return units, nil
+}
+
+func (a *Analyzer) splitFile(filename string) ([]workUnit, error) {
+	whole := []workUnit{wholeFileUnit(filename)}
+	if util.IsStream(filename) {
+		return whole, nil
+	}
+	compressed, err := util.IsCompressed(filename)
+	if err != nil {
+		return nil, err
+	}
+	if compressed {
+		return whole, nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fileInfo, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fileInfo.Size()
+	var start int64
+	if !a.Config.Filter.TimeFrom.IsZero() {
+		start, err = timeseek.Seek(f, 0, size, a.logParser, a.Config.Filter.TimeFrom)
+		if err != nil {
+			return nil, err
+		}
+	}
+	n := min(int64(a.Config.Jobs), (size-start)/minChunkSize)
+	if n < 2 {
+		if start == 0 {
+			return whole, nil
+		}
+		return []workUnit{{filename: filename, start: start, end: -1}}, nil
+	}
+	bounds, err := lineAlignedBounds(f, start, size, int(n))
+	if err != nil {
+		return nil, err
+	}
+	var units []workUnit
+	for i := range len(bounds) - 1 {
+		units = append(units, workUnit{filename: filename, start: bounds[i], end: bounds[i+1]})
+	}
+	// The file might be still growing
+	units[len(units)-1].end = -1
+	return units, nil
+}
+
+// lineAlignedBounds splits [start, end) into at most n parts, returning boundaries
+// (including start and end) which are all at beginning of lines.
+func lineAlignedBounds(r io.ReaderAt, start, end int64, n int) ([]int64, error) {
+	bounds := []int64{start}
+	for i := 1; i < n; i++ {
+		pos := start + (end-start)*int64(i)/int64(n)
+		if pos <= bounds[len(bounds)-1] {
+			// Previous line is too long
+			continue
+		}
+		next, err := nextLineStart(r, pos, end)
+		if err != nil {
+			return nil, err
+		}
+		if next >= end {
+			break
+		}
+		bounds = append(bounds, next)
+	}
+	return append(bounds, end), nil
+}
+
+// nextLineStart returns offset after the first newline at or after pos, or size if not found.
+func nextLineStart(r io.ReaderAt, pos, size int64) (int64, error) {
+	buf := make([]byte, 64*1024)
+	for pos < size {
+		n, err := r.ReadAt(buf, pos)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return pos + int64(i) + 1, nil
+		}
+		pos += int64(n)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return 0, err
+		}
+	}
+	return size, nil
+}
+
+// newWorker creates an analyzer sharing config with a, but having its own parser and stats.
+func (a *Analyzer) newWorker() (*Analyzer, error) {
+	logParser, err := newParser(a.Config)
+	if err != nil {
+		return nil, err
+	}
+	w := &Analyzer{
+		Config:    a.Config,
+		stats:     make(map[StatKey]IPStats),
+		logParser: logParser,
+		logger:    a.logger,
+		bar:       a.bar,
+	}
+	if a.dirStats != nil {
+		w.dirStats = make(map[string]*DirectoryTotalStats)
+	}
+	if a.hourly != nil {
+		w.hourly = make(map[hourKey]counter)
+	}
+	if a.approx != nil {
+		w.approx = newApproxStats(a.approx.capacity)
+	}
+	if a.rates != nil {
+		w.rates = make(map[StatKey]rateStats)
+	}
+	return w, nil
+}
+
+func (a *Analyzer) openUnit(u workUnit) (io.ReadCloser, error) {
+	if u.isWhole() {
+		return timeseek.OpenFrom(u.filename, a.openFile, a.logParser, a.Config.Filter.TimeFrom)
+	}
+	f, err := os.Open(u.filename)
+	if err != nil {
+		return nil, err
+	}
+	if u.end < 0 {
+		if _, err := f.Seek(u.start, io.SeekStart); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return f, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(f, u.start, u.end-u.start), f}, nil
+}
+
+func (a *Analyzer) analyzeUnit(u workUnit, bar *progressbar.ProgressBar) error {
+	r, err := a.openUnit(u)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var lines int64
+	defer func() {
+		bar.Add64(lines % progressBatch)
+	}()
+	iter := timeseek.Until(fileiter.NewWithScanner(r), a.logParser, a.Config.Filter.TimeTo)
+	for {
+		line, err := iter.Next()
+		if err != nil {
+			return err
+		}
+		if line == nil {
+			return nil
+		}
+		lines++
+		if lines%progressBatch == 0 {
+			bar.Add64(progressBatch)
+		}
+		if err := a.processLine(line); err != nil {
+			a.logger.Printf("analyze error: %v", err)
+		}
+	}
+}
+
+func (a *Analyzer) mergeFrom(w *Analyzer) {
+	if w.latestTime.After(a.latestTime) {
+		a.latestTime = w.latestTime
+	}
+	a.mergeStats(w.stats, w.approx)
+	a.mergeRates(w.rates)
+	for dir, stats := range w.dirStats {
+		if s, ok := a.dirStats[dir]; ok {
+			s.MergeWith(stats)
+		} else {
+			a.dirStats[dir] = stats
+		}
+	}
+	for key, c := range w.hourly {
+		h := a.hourly[key]
+		h.Size += c.Size
+		h.Requests += c.Requests
+		a.hourly[key] = h
+	}
+}
@@ -0,0 +1,85 @@
+package analyze
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineAlignedBounds(t *testing.T) {
+	as := assert.New(t)
+	content := "aaaa\nbbbbbbbbbbbbbbbbbbbb\ncc\ndddd\n"
+	r := strings.NewReader(content)
+	bounds, err := lineAlignedBounds(r, 0, int64(len(content)), 4)
+	as.NoError(err)
+	as.Equal([]int64{0, 26, int64(len(content))}, bounds)
+	for _, b := range bounds[1 : len(bounds)-1] {
+		as.Equal(byte('\n'), content[b-1])
+	}
+
+	bounds, err = lineAlignedBounds(r, 0, int64(len(content)), 1)
+	as.NoError(err)
+	as.Equal([]int64{0, int64(len(content))}, bounds)
+}
+
+func TestAnalyzeFilesParallel(t *testing.T) {
+	as := assert.New(t)
+	dir := t.TempDir()
+	var sb strings.Builder
+	for i := range 2000 {
+		fmt.Fprintf(&sb, "10.0.%d.%d - - [01/Jan/2024:10:%02d:%02d +0000] \"GET /dir%d/file HTTP/1.1\" 200 %d \"-\" \"ua%d\"\n",
+			i%7, i%200, i/60%60, i%60, i%5, 100+i, i%3)
+	}
+	filename := filepath.Join(dir, "access.log")
+	if err := os.WriteFile(filename, []byte(sb.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldMinChunkSize := minChunkSize
+	minChunkSize = 1024
+	defer func() { minChunkSize = oldMinChunkSize }()
+
+	analyzeWithJobs := func(jobs int) *Analyzer {
+		c := DefaultConfig()
+		c.NoNetstat = true
+		c.Parser = "nginx-combined"
+		c.Filter.Threshold = 0
+		c.DirAnalyze = true
+		c.Analyze = true
+		c.Jobs = jobs
+		c.Report = filepath.Join(dir, "report.html")
+		a, err := NewAnalyzer(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		as.NoError(a.AnalyzeFiles([]string{filename, filename}))
+		return a
+	}
+	expected := analyzeWithJobs(1)
+	a := analyzeWithJobs(4)
+	units, err := a.planUnits([]string{filename})
+	as.NoError(err)
+	as.Len(units, 4)
+
+	as.Equal(len(expected.stats), len(a.stats))
+	for k, v := range expected.stats {
+		s := a.stats[k]
+		as.Equal(v.Size, s.Size, k)
+		as.Equal(v.Requests, s.Requests, k)
+		as.Equal(v.LastURL, s.LastURL, k)
+		as.Equal(v.LastURLUpdate, s.LastURLUpdate, k)
+		as.Equal(v.LastURLAccess, s.LastURLAccess, k)
+		as.Equal(v.UAStore, s.UAStore, k)
+		as.Equal(v.DirStats, s.DirStats, k)
+	}
+	as.Equal(len(expected.dirStats), len(a.dirStats))
+	for dir, v := range expected.dirStats {
+		as.Equal(v, a.dirStats[dir], dir)
+	}
+	as.NotEmpty(a.hourly)
+	as.Equal(expected.hourly, a.hourly)
+}
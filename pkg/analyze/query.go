@@ -0,0 +1,159 @@
+package analyze
+
+import (
+	"cmp"
+	"net/netip"
+	"slices"
+)
+
+// Query selects items returned by query API.
+type Query struct {
+	SortBy SortByFlag
+	// Stats of this server only, or total of all servers in run mode if empty
+	Server string
+	// At most this many items, 0 for no limit
+	Limit int
+}
+
+// DefaultQuery returns a query like the top table printed.
+func (a *Analyzer) DefaultQuery() Query {
+	return Query{
+		SortBy: a.Config.SortBy,
+		Server: a.Config.Filter.Server,
+		Limit:  a.Config.TopN,
+	}
+}
+
+// PrefixDetails is stats of a single client prefix.
+type PrefixDetails struct {
+	TopRecord
+	UserAgentList []string          `json:"user_agent_list,omitempty"`
+	Directories   []PrefixDirRecord `json:"directories,omitempty"`
+}
+
+// PrefixDirRecord is traffic of a client prefix to a directory.
+type PrefixDirRecord struct {
+	Directory string `json:"directory"`
+	Bytes     uint64 `json:"bytes"`
+	Requests  uint64 `json:"requests"`
+	AvgBytes  uint64 `json:"avg_bytes"`
+}
+
+func limit[T any](items []T, n int) []T {
+	if n > 0 && len(items) > n {
+		return items[:n]
+	}
+	return items
+}
+
+// lockForQuery locks a if needed, and returns the function to unlock it.
+func (a *Analyzer) lockForQuery() func() {
+	unlock := func() {}
+	if a.Config.UseLock() {
+		a.mu.Lock()
+		unlock = a.mu.Unlock
+	}
+	if a.recent != nil {
+		a.advanceRecentToNow()
+	}
+	return unlock
+}
+
+func (a *Analyzer) activeConns() map[netip.Prefix]int {
+	activeConn := make(map[netip.Prefix]int)
+	if !a.Config.NoNetstat {
+		a.GetActiveConns(activeConn)
+	}
+	return activeConn
+}
+
+// QueryTop returns top client prefixes.
+func (a *Analyzer) QueryTop(q Query) []TopRecord {
+	activeConn := a.activeConns()
+	defer a.lockForQuery()()
+
+	keys := limit(a.SortedKeys(q.SortBy, q.Server), q.Limit)
+	records := make([]TopRecord, 0, len(keys))
+	for _, key := range keys {
+		records = append(records, a.newTopRecord(key, a.stats[key], activeConn))
+	}
+	return records
+}
+
+// QueryServers returns total size of each server, like PrintTotal.
+func (a *Analyzer) QueryServers(q Query) []ServerRecord {
+	defer a.lockForQuery()()
+
+	totals := make(map[string]uint64)
+	for key, stats := range a.stats {
+		if q.Server == "" || key.Server == q.Server {
+			totals[key.Server] += stats.Size
+		}
+	}
+	records := make([]ServerRecord, 0, len(totals))
+	for server, size := range totals {
+		records = append(records, ServerRecord{server, size})
+	}
+	slices.SortFunc(records, func(l, r ServerRecord) int {
+		return cmp.Or(cmp.Compare(r.Bytes, l.Bytes), cmp.Compare(l.Server, r.Server))
+	})
+	return limit(records, q.Limit)
+}
+
+// QueryPrefix returns stats of prefix, with at most q.Limit user-agents and directories.
+func (a *Analyzer) QueryPrefix(prefix netip.Prefix, q Query) (PrefixDetails, bool) {
+	activeConn := a.activeConns()
+	defer a.lockForQuery()()
+
+	key := StatKey{q.Server, prefix.Masked()}
+	stats, ok := a.stats[key]
+	if !ok {
+		return PrefixDetails{}, false
+	}
+	d := PrefixDetails{TopRecord: a.newTopRecord(key, stats, activeConn)}
+	for ua := range stats.UAStore {
+		d.UserAgentList = append(d.UserAgentList, ua.Value())
+	}
+	slices.Sort(d.UserAgentList)
+	d.UserAgentList = limit(d.UserAgentList, q.Limit)
+
+	for dir, ds := range stats.DirStats {
+		d.Directories = append(d.Directories, PrefixDirRecord{
+			Directory: dir,
+			Bytes:     ds.Size,
+			Requests:  ds.Requests,
+			AvgBytes:  ds.Size / ds.Requests,
+		})
+	}
+	slices.SortFunc(d.Directories, func(l, r PrefixDirRecord) int {
+		c := cmp.Compare(r.Bytes, l.Bytes)
+		if q.SortBy == SortByRequests {
+			c = cmp.Compare(r.Requests, l.Requests)
+		}
+		return cmp.Or(c, cmp.Compare(l.Directory, r.Directory))
+	})
+	d.Directories = limit(d.Directories, q.Limit)
+	return d, true
+}
+
+// QueryDirs returns the directory table as dir-analyze, of all servers.
+// It's false if directory stats are not kept.
+func (a *Analyzer) QueryDirs(q Query) ([]DirRecord, bool) {
+	defer a.lockForQuery()()
+
+	if a.dirStats == nil {
+		return nil, false
+	}
+	return limit(a.dirRecords(q.SortBy), q.Limit), true
+}
+
+// sortDirRecords sorts by requests, or size for other sort orders.
+func sortDirRecords(records []DirRecord, sortBy SortByFlag) {
+	slices.SortFunc(records, func(l, r DirRecord) int {
+		c := cmp.Compare(r.Bytes, l.Bytes)
+		if sortBy == SortByRequests {
+			c = cmp.Compare(r.Requests, l.Requests)
+		}
+		return cmp.Or(c, cmp.Compare(l.Directory, r.Directory))
+	})
+}
@@ -0,0 +1,120 @@
+package analyze
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taoky/ayano/pkg/parser"
+)
+
+func newQueryAnalyzer(t *testing.T) *Analyzer {
+	a := newTestAnalyzer(t, func(c *AnalyzerConfig) {
+		c.Listen = "127.0.0.1:0"
+		c.TrackDirs = true
+	})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := []parser.LogItem{
+		{Client: "10.0.0.1", Size: 100, URL: "/debian/a.deb", Useragent: "apt", Server: "mirror1"},
+		{Client: "10.0.0.2", Size: 300, URL: "/ubuntu/b.iso", Useragent: "curl", Server: "mirror1"},
+		{Client: "10.0.1.1", Size: 50, URL: "/debian/c.deb", Useragent: "apt", Server: "mirror1"},
+		{Client: "10.0.1.1", Size: 60, URL: "/debian/d.deb", Useragent: "apt", Server: "mirror1"},
+		{Client: "10.0.1.1", Size: 1000, URL: "/debian/a.deb", Useragent: "wget", Server: "mirror2"},
+	}
+	for i, item := range items {
+		item.Time = now.Add(time.Duration(i) * time.Second)
+		if err := a.handleLogItem(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return a
+}
+
+func TestQueryTop(t *testing.T) {
+	as := assert.New(t)
+	a := newQueryAnalyzer(t)
+
+	q := a.DefaultQuery()
+	top := a.QueryTop(q)
+	as.Len(top, 2)
+	as.Equal("10.0.1.0/24", top[0].CIDR)
+	as.Equal(uint64(1110), top[0].Bytes)
+	as.Equal(2, top[0].UserAgents)
+
+	q.SortBy = SortByRequests
+	q.Limit = 1
+	top = a.QueryTop(q)
+	as.Len(top, 1)
+	as.Equal("10.0.1.0/24", top[0].CIDR)
+	as.Equal(uint64(3), top[0].Requests)
+
+	q.Server = "mirror1"
+	q.SortBy = SortBySize
+	top = a.QueryTop(q)
+	as.Len(top, 1)
+	as.Equal("mirror1", top[0].Server)
+	as.Equal("10.0.0.0/24", top[0].CIDR)
+	as.Equal(uint64(400), top[0].Bytes)
+}
+
+func TestQueryServers(t *testing.T) {
+	as := assert.New(t)
+	a := newQueryAnalyzer(t)
+
+	q := a.DefaultQuery()
+	as.Equal([]ServerRecord{{"", 1510}, {"mirror2", 1000}, {"mirror1", 510}}, a.QueryServers(q))
+	q.Limit = 1
+	as.Equal([]ServerRecord{{"", 1510}}, a.QueryServers(q))
+	q.Server = "mirror1"
+	as.Equal([]ServerRecord{{"mirror1", 510}}, a.QueryServers(q))
+}
+
+func TestQueryPrefix(t *testing.T) {
+	as := assert.New(t)
+	a := newQueryAnalyzer(t)
+
+	q := a.DefaultQuery()
+	d, ok := a.QueryPrefix(netip.MustParsePrefix("10.0.1.1/24"), q)
+	as.True(ok)
+	as.Equal("10.0.1.0/24", d.CIDR)
+	as.Equal([]string{"apt", "wget"}, d.UserAgentList)
+	as.Equal([]PrefixDirRecord{{Directory: "/debian", Bytes: 1110, Requests: 3, AvgBytes: 370}}, d.Directories)
+
+	q.Server = "mirror2"
+	d, ok = a.QueryPrefix(netip.MustParsePrefix("10.0.1.0/24"), q)
+	as.True(ok)
+	as.Equal([]string{"wget"}, d.UserAgentList)
+
+	_, ok = a.QueryPrefix(netip.MustParsePrefix("10.0.2.0/24"), q)
+	as.False(ok)
+}
+
+func TestQueryDirs(t *testing.T) {
+	as := assert.New(t)
+	a := newQueryAnalyzer(t)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	q := a.DefaultQuery()
+	dirs, ok := a.QueryDirs(q)
+	as.True(ok)
+	as.Equal([]DirRecord{
+		{Directory: "/debian", Bytes: 1210, Requests: 4, AvgBytes: 302, IPs: 2, LastAccess: now.Add(4 * time.Second)},
+		{Directory: "/ubuntu", Bytes: 300, Requests: 1, AvgBytes: 300, IPs: 1, LastAccess: now.Add(time.Second)},
+	}, dirs)
+
+	q.SortBy = SortByRequests
+	q.Limit = 1
+	dirs, ok = a.QueryDirs(q)
+	as.True(ok)
+	as.Len(dirs, 1)
+	as.Equal("/debian", dirs[0].Directory)
+
+	a = newTestAnalyzer(t, func(c *AnalyzerConfig) {
+		c.Listen = "127.0.0.1:0"
+	})
+	as.Nil(a.dirStats)
+	_, ok = a.QueryDirs(q)
+	as.False(ok)
+}
@@ -0,0 +1,135 @@
+package analyze
+
+import (
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// rateStats tracks throughput of a StatKey by log time, in intervals of refresh seconds.
+// It's kept apart from IPStats, as it's needed only when rates are shown or sorted by.
+type rateStats struct {
+	FirstAccess time.Time
+	// Current interval, and bytes in it
+	Start time.Time
+	Bytes uint64
+	// Rate of the interval before current one (0 if idle), and the highest rate
+	Last float64
+	Peak float64
+}
+
+// update accounts size into rate interval of t.
+func (r rateStats) update(t time.Time, size uint64, interval time.Duration) rateStats {
+	if r.FirstAccess.IsZero() || t.Before(r.FirstAccess) {
+		r.FirstAccess = t
+	}
+	start := t.Truncate(interval)
+	if start.After(r.Start) {
+		if !r.Start.IsZero() {
+			rate := float64(r.Bytes) / interval.Seconds()
+			r.Peak = max(r.Peak, rate)
+			if start.Equal(r.Start.Add(interval)) {
+				r.Last = rate
+			} else {
+				// Idle in between
+				r.Last = 0
+			}
+		}
+		r.Start = start
+		r.Bytes = 0
+	}
+	// Lines slightly out of order are counted in current interval
+	r.Bytes += size
+	return r
+}
+
+func (r rateStats) mergeWith(other rateStats) rateStats {
+	if r.FirstAccess.IsZero() || (!other.FirstAccess.IsZero() && other.FirstAccess.Before(r.FirstAccess)) {
+		r.FirstAccess = other.FirstAccess
+	}
+	r.Peak = max(r.Peak, other.Peak)
+	if r.Start.Equal(other.Start) {
+		r.Bytes += other.Bytes
+		r.Last += other.Last
+	} else if other.Start.After(r.Start) {
+		r.Start = other.Start
+		r.Bytes = other.Bytes
+		r.Last = other.Last
+	}
+	return r
+}
+
+// current returns bytes per second in the last complete interval before now.
+func (r rateStats) current(now time.Time, interval time.Duration) float64 {
+	switch {
+	case !now.Before(r.Start.Add(2 * interval)):
+		return 0
+	case !now.Before(r.Start.Add(interval)):
+		return float64(r.Bytes) / interval.Seconds()
+	default:
+		return r.Last
+	}
+}
+
+// average returns bytes per second over the active span, from first access to last.
+func (r rateStats) average(i IPStats, interval time.Duration) float64 {
+	span := max(i.LastURLAccess.Sub(r.FirstAccess), interval)
+	return float64(i.Size) / span.Seconds()
+}
+
+// peak returns the highest rate of an interval, including the current one.
+func (r rateStats) peak(interval time.Duration) float64 {
+	return max(r.Peak, float64(r.Bytes)/interval.Seconds())
+}
+
+// TracksRate reports whether rates are tracked, which is when they're shown
+// (in run, analyze and merge), or sorted by.
+func (c AnalyzerConfig) TracksRate() bool {
+	return (!c.Daemon && !c.DirAnalyze) || c.SortBy == SortByRate
+}
+
+func (a *Analyzer) rateInterval() time.Duration {
+	return time.Duration(max(a.Config.RefreshSec, 1)) * time.Second
+}
+
+// rate returns current rate when following logs, or average rate in analyze mode,
+// where "current" is meaningless.
+func (a *Analyzer) rate(key StatKey) float64 {
+	if a.Config.Analyze || a.Config.DirAnalyze {
+		return a.avgRate(key)
+	}
+	return a.rates[key].current(a.latestTime, a.rateInterval())
+}
+
+func (a *Analyzer) avgRate(key StatKey) float64 {
+	if a.rates == nil {
+		return 0
+	}
+	return a.rates[key].average(a.stats[key], a.rateInterval())
+}
+
+func (a *Analyzer) peakRate(key StatKey) float64 {
+	return a.rates[key].peak(a.rateInterval())
+}
+
+// mergeRates merges rates of keys still in a.stats.
+func (a *Analyzer) mergeRates(rates map[StatKey]rateStats) {
+	if a.rates == nil {
+		return
+	}
+	for k, v := range rates {
+		if _, ok := a.stats[k]; ok {
+			a.rates[k] = a.rates[k].mergeWith(v)
+		}
+	}
+}
+
+// deleteStats removes key from a.stats, and its rate.
+func (a *Analyzer) deleteStats(key StatKey) {
+	delete(a.stats, key)
+	delete(a.rates, key)
+}
+
+func humanizeRate(rate float64) string {
+	return humanize.IBytes(uint64(rate)) + "/s"
+}
@@ -0,0 +1,51 @@
+package analyze
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRate(t *testing.T) {
+	as := assert.New(t)
+	interval := 5 * time.Second
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time {
+		return start.Add(time.Duration(sec) * time.Second)
+	}
+
+	var r rateStats
+	r = r.update(at(0), 1000, interval)
+	r = r.update(at(3), 1000, interval)
+	as.Equal(0.0, r.current(at(4), interval))
+	// First interval completed
+	as.Equal(400.0, r.current(at(6), interval))
+	r = r.update(at(7), 500, interval)
+	as.Equal(400.0, r.current(at(8), interval))
+	as.Equal(100.0, r.current(at(11), interval))
+	as.Equal(400.0, r.peak(interval))
+	// Idle
+	as.Equal(0.0, r.current(at(20), interval))
+	r = r.update(at(30), 10000, interval)
+	as.Equal(0.0, r.Last)
+	as.Equal(2000.0, r.peak(interval))
+	as.Equal(at(0), r.FirstAccess)
+
+	as.Equal(500.0, r.average(IPStats{Size: 12500, LastURLAccess: at(25)}, interval))
+}
+
+func TestTracksRate(t *testing.T) {
+	as := assert.New(t)
+	run := newTestAnalyzer(t, func(c *AnalyzerConfig) {})
+	as.NotNil(run.rates)
+	daemon := newTestAnalyzer(t, func(c *AnalyzerConfig) {
+		c.Daemon = true
+	})
+	as.Nil(daemon.rates)
+	daemon = newTestAnalyzer(t, func(c *AnalyzerConfig) {
+		c.Daemon = true
+		c.SortBy = SortByRate
+	})
+	as.NotNil(daemon.rates)
+}
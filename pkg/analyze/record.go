@@ -0,0 +1,108 @@
+package analyze
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/taoky/ayano/pkg/parser"
+	"github.com/taoky/ayano/pkg/rules"
+)
+
+// RecordFormat is the format of lines printed in daemon mode.
+type RecordFormat string
+
+const (
+	RecordText RecordFormat = "text"
+	RecordJSON RecordFormat = "json"
+)
+
+func (r RecordFormat) String() string {
+	return string(r)
+}
+
+func (r *RecordFormat) Set(value string) error {
+	switch RecordFormat(value) {
+	case RecordText, RecordJSON:
+		*r = RecordFormat(value)
+	default:
+		return fmt.Errorf("must be one of: %v", []RecordFormat{RecordText, RecordJSON})
+	}
+	return nil
+}
+
+func (r RecordFormat) Type() string {
+	return "string"
+}
+
+// daemonRecord is a JSON line printed in daemon mode.
+// Keep time and cidr first, followed by rule and condition, for simpler fail2ban filters.
+type daemonRecord struct {
+	Time       time.Time `json:"time"`
+	CIDR       string    `json:"cidr"`
+	Rule       string    `json:"rule,omitempty"`
+	Condition  string    `json:"condition,omitempty"`
+	Value      uint64    `json:"value,omitempty"`
+	Server     string    `json:"server,omitempty"`
+	Bytes      uint64    `json:"bytes"`
+	Requests   uint64    `json:"requests"`
+	FirstSeen  time.Time `json:"first_seen"`
+	URL        string    `json:"url"`
+	UserAgents int       `json:"user_agents"`
+}
+
+func newDaemonRecord(prefix netip.Prefix, stats IPStats, item parser.LogItem) daemonRecord {
+	r := daemonRecord{
+		// Without fractional seconds, which fail2ban does not expect
+		Time:      time.Now().Truncate(time.Second),
+		CIDR:      prefix.String(),
+		Server:    item.Server,
+		Bytes:     stats.Size,
+		Requests:  stats.Requests,
+		FirstSeen: stats.FirstSeen,
+		URL:       item.URL,
+	}
+	r.UserAgents, _ = stats.UserAgents()
+	return r
+}
+
+func (a *Analyzer) writeDaemonRecord(r daemonRecord) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		a.logger.Printf("failed to encode record: %v", err)
+		return
+	}
+	data = append(data, '\n')
+	if _, err := a.logger.Writer().Write(data); err != nil {
+		a.logger.Printf("failed to write record: %v", err)
+	}
+}
+
+// printDeltaRecord reports a client having downloaded another Config.PrintDelta.
+func (a *Analyzer) printDeltaRecord(prefix netip.Prefix, stats IPStats, item parser.LogItem) {
+	if a.Config.Record == RecordJSON {
+		a.writeDaemonRecord(newDaemonRecord(prefix, stats, item))
+		return
+	}
+	a.logger.Printf("%s %s %s %s",
+		prefix.String(),
+		humanize.IBytes(stats.Size),
+		stats.FirstSeen.Format(TimeFormat),
+		item.URL)
+}
+
+// printRuleRecord reports a client matching a rule.
+func (a *Analyzer) printRuleRecord(m rules.Match, stats IPStats, item parser.LogItem) {
+	if a.Config.Record == RecordJSON {
+		r := newDaemonRecord(m.Prefix, stats, item)
+		r.Rule = m.Rule.Name
+		r.Condition = m.Rule.Condition()
+		r.Value = m.Value
+		r.FirstSeen = m.First
+		a.writeDaemonRecord(r)
+		return
+	}
+	a.logger.Print(m)
+}
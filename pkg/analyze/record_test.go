@@ -0,0 +1,93 @@
+package analyze
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taoky/ayano/pkg/parser"
+	"github.com/taoky/ayano/pkg/rules"
+)
+
+func TestDaemonRecord(t *testing.T) {
+	as := assert.New(t)
+	a := newTestAnalyzer(t, func(c *AnalyzerConfig) {
+		c.Daemon = true
+		c.PrintDelta = 1000
+		c.Record = RecordJSON
+	})
+	var buf bytes.Buffer
+	a.logger.SetOutput(&buf)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		as.NoError(a.handleLogItem(parser.LogItem{
+			Client:    "10.0.0.1",
+			Size:      600,
+			URL:       "/debian/a b.iso",
+			Time:      now.Add(time.Duration(i) * time.Second),
+			Useragent: "curl",
+			Server:    "mirror",
+		}))
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	as.Len(lines, 1)
+	as.Regexp(`^\{"time":"[^"]+","cidr":"10\.0\.0\.0/24",`, lines[0])
+	var r daemonRecord
+	as.NoError(json.Unmarshal([]byte(lines[0]), &r))
+	as.Equal(daemonRecord{
+		Time:       r.Time,
+		CIDR:       "10.0.0.0/24",
+		Server:     "mirror",
+		Bytes:      1200,
+		Requests:   2,
+		FirstSeen:  now.Add(time.Second),
+		URL:        "/debian/a b.iso",
+		UserAgents: 1,
+	}, r)
+
+	buf.Reset()
+	rule := rules.Rule{Name: "bulk", Requests: 10}
+	a.printRuleRecord(rules.Match{Rule: &rule, Prefix: netip.MustParsePrefix("10.0.0.0/24"), Value: 42, First: now}, a.stats[StatKey{Prefix: netip.MustParsePrefix("10.0.0.0/24")}], parser.LogItem{URL: "/x"})
+	as.Regexp(`^\{"time":"[^"]+","cidr":"10\.0\.0\.0/24","rule":"bulk","condition":"requests","value":42,"bytes":1800,`, buf.String())
+}
+
+func TestRulesDefaultThreshold(t *testing.T) {
+	as := assert.New(t)
+	filename := filepath.Join(t.TempDir(), "rules.yaml")
+	as.NoError(os.WriteFile(filename, []byte(`
+rules:
+  - name: hammer
+    requests: 3
+    within: 1m
+`), 0o644))
+	c := DefaultConfig()
+	c.NoNetstat = true
+	c.Parser = "nginx-combined"
+	c.Daemon = true
+	c.Rules = filename
+	c.Record = RecordJSON
+	a, err := NewAnalyzer(c)
+	as.NoError(err)
+	var buf bytes.Buffer
+	a.logger.SetOutput(&buf)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		as.NoError(a.handleLogItem(parser.LogItem{
+			Client: "10.0.0.1",
+			Size:   100,
+			URL:    "/debian/a.deb",
+			Time:   now.Add(time.Duration(i) * time.Second),
+		}))
+	}
+	as.Contains(buf.String(), `"rule":"hammer","condition":"requests","value":4,`)
+	// Small requests are not counted in stats
+	as.Empty(a.stats)
+}
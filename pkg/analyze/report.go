@@ -0,0 +1,260 @@
+package analyze
+
+import (
+	"cmp"
+	_ "embed"
+	"fmt"
+	"html"
+	"html/template"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/taoky/ayano/pkg/parser"
+)
+
+//go:embed report.html
+var reportHTML string
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ibytes": humanize.IBytes,
+	"time": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(TimeFormat)
+	},
+}).Parse(reportHTML))
+
+// Hour of log items without time
+const unknownHour = math.MinInt64
+
+// Charts show at most this many hours
+const maxChartHours = 90 * 24
+
+// Time zone of hours in report
+var reportLoc = time.Local
+
+type hourKey struct {
+	server string
+	// Hours since Unix epoch, of local wall clock (see localHour)
+	hour int64
+}
+
+// localHour returns hours since Unix epoch as if wall clock of t in reportLoc were UTC,
+// so that hours are aligned to local midnight even with fractional offsets.
+func localHour(t time.Time) int64 {
+	t = t.In(reportLoc)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, time.UTC).Unix() / 3600
+}
+
+// hourClock returns local wall clock of h, in UTC location, for formatting.
+func hourClock(h int64) time.Time {
+	return time.Unix(h*3600, 0).UTC()
+}
+
+// hourTime returns the time of h in reportLoc.
+func hourTime(h int64) time.Time {
+	c := hourClock(h)
+	return time.Date(c.Year(), c.Month(), c.Day(), c.Hour(), 0, 0, 0, reportLoc)
+}
+
+func (a *Analyzer) addHourly(item parser.LogItem) {
+	key := hourKey{item.Server, unknownHour}
+	if !item.Time.IsZero() {
+		key.hour = localHour(item.Time)
+	}
+	c := a.hourly[key]
+	c.Size += item.Size
+	c.Requests++
+	a.hourly[key] = c
+}
+
+type reportServer struct {
+	Server   string
+	Size     uint64
+	Requests uint64
+}
+
+type reportData struct {
+	Generated time.Time
+	From, To  time.Time
+	Server    string
+	SortBy    SortByFlag
+	Approx    bool
+
+	Servers       []reportServer
+	BytesChart    template.HTML
+	RequestsChart template.HTML
+	// Requests with time out of charts
+	Uncharted uint64
+
+	Top  []TopRecord
+	Dirs []DirRecord
+}
+
+// WriteReport writes an HTML report of top prefixes, directories and hourly traffic.
+// Config.Report must be set.
+func (a *Analyzer) WriteReport(filename string, sortBy SortByFlag) error {
+	if a.hourly == nil {
+		return fmt.Errorf("report is not enabled")
+	}
+	if a.Config.UseLock() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+	}
+
+	d := reportData{
+		Generated: time.Now(),
+		Server:    a.Config.Filter.Server,
+		SortBy:    sortBy,
+		Approx:    a.approx != nil,
+		Dirs:      limit(a.dirRecords(sortBy), a.Config.TopN),
+	}
+	for _, key := range limit(a.SortedKeys(sortBy, a.Config.Filter.Server), a.Config.TopN) {
+		d.Top = append(d.Top, a.newTopRecord(key, a.stats[key], nil))
+	}
+
+	servers := make(map[string]*reportServer)
+	hours := make(map[int64]counter)
+	for key, c := range a.hourly {
+		s, ok := servers[key.server]
+		if !ok {
+			s = &reportServer{Server: key.server}
+			servers[key.server] = s
+		}
+		s.Size += c.Size
+		s.Requests += c.Requests
+		if key.hour != unknownHour {
+			h := hours[key.hour]
+			h.Size += c.Size
+			h.Requests += c.Requests
+			hours[key.hour] = h
+		}
+	}
+	for _, s := range servers {
+		d.Servers = append(d.Servers, *s)
+	}
+	slices.SortFunc(d.Servers, func(l, r reportServer) int {
+		return cmp.Or(cmp.Compare(r.Size, l.Size), cmp.Compare(l.Server, r.Server))
+	})
+
+	if first, last, ok := a.chartRange(hours); ok {
+		d.From = hourTime(first)
+		d.To = hourTime(last + 1)
+		sizes := make([]uint64, last-first+1)
+		requests := make([]uint64, last-first+1)
+		for h, c := range hours {
+			if h < first || h > last {
+				d.Uncharted += c.Requests
+				continue
+			}
+			sizes[h-first] = c.Size
+			requests[h-first] = c.Requests
+		}
+		d.BytesChart = hourlyChart(first, sizes, humanize.IBytes)
+		d.RequestsChart = hourlyChart(first, requests, func(v uint64) string {
+			return humanize.Comma(int64(v)) + " reqs"
+		})
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := reportTemplate.Execute(f, d); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// chartRange returns the hours to chart: within --time-from and --time-to if given,
+// and at most maxChartHours with most requests, so that a few lines with wrong time
+// (or stats loaded from snapshot) do not stretch charts.
+func (a *Analyzer) chartRange(hours map[int64]counter) (first, last int64, ok bool) {
+	keys := slices.Sorted(maps.Keys(hours))
+	if from := a.Config.Filter.TimeFrom; !from.IsZero() {
+		keys = slices.DeleteFunc(keys, func(h int64) bool { return h < localHour(from) })
+	}
+	if to := a.Config.Filter.TimeTo; !to.IsZero() {
+		keys = slices.DeleteFunc(keys, func(h int64) bool { return h > localHour(to) })
+	}
+	var best, sum uint64
+	i := 0
+	for _, h := range keys {
+		sum += hours[h].Requests
+		for h-keys[i] >= maxChartHours {
+			sum -= hours[keys[i]].Requests
+			i++
+		}
+		if sum > best {
+			best = sum
+			first, last, ok = keys[i], h, true
+		}
+	}
+	return first, last, ok
+}
+
+// Steps of labels on the x-axis of hourly charts, in hours
+var chartLabelSteps = []int{1, 2, 3, 6, 12, 24, 48, 7 * 24, 14 * 24, 28 * 24}
+
+// hourlyChart draws values of consecutive local hours from first as an SVG bar chart.
+func hourlyChart(first int64, values []uint64, format func(uint64) string) template.HTML {
+	const (
+		width, height = 960, 240
+		left, bottom  = 80, 24
+		plotW, plotH  = width - left, height - bottom - 8
+	)
+	maxValue := uint64(1)
+	for _, v := range values {
+		maxValue = max(maxValue, v)
+	}
+	barW := float64(plotW) / float64(len(values))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">`, width, height)
+	// Horizontal grid lines with labels
+	for i := 0; i <= 2; i++ {
+		y := 8 + plotH*(2-i)/2
+		fmt.Fprintf(&b, `<line class="grid" x1="%d" y1="%d" x2="%d" y2="%d"/>`, left, y, width, y)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`,
+			left-6, y+4, html.EscapeString(format(maxValue*uint64(i)/2)))
+	}
+
+	step := chartLabelSteps[len(chartLabelSteps)-1]
+	for _, s := range chartLabelSteps {
+		if len(values)/s <= 12 {
+			step = s
+			break
+		}
+	}
+	for i, v := range values {
+		t := hourClock(first + int64(i))
+		x := float64(left) + barW*float64(i)
+		h := float64(plotH) * float64(v) / float64(maxValue)
+		fmt.Fprintf(&b, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f"><title>%s: %s</title></rect>`,
+			x, float64(8+plotH)-h, max(barW-1, 0.5), h,
+			t.Format("2006-01-02 15:00"), html.EscapeString(format(v)))
+		if isChartLabel(t, step) {
+			label := t.Format("01-02 15:00")
+			if step >= 24 {
+				label = t.Format("01-02")
+			}
+			fmt.Fprintf(&b, `<text x="%.2f" y="%d" text-anchor="middle">%s</text>`, x+barW/2, height-6, label)
+		}
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+func isChartLabel(t time.Time, step int) bool {
+	if step < 24 {
+		return t.Hour()%step == 0
+	}
+	return t.Hour() == 0 && t.YearDay()%(step/24) == 0
+}
@@ -0,0 +1,82 @@
+package analyze
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taoky/ayano/pkg/parser"
+)
+
+func TestWriteReport(t *testing.T) {
+	as := assert.New(t)
+	filename := filepath.Join(t.TempDir(), "report.html")
+	a := newTestAnalyzer(t, func(c *AnalyzerConfig) {
+		c.Analyze = true
+		c.Report = filename
+	})
+
+	start := time.Date(2024, 1, 1, 0, 30, 0, 0, time.Local)
+	items := []parser.LogItem{
+		{Client: "10.0.0.1", Size: 1 << 20, URL: "/debian/a.deb", Useragent: "apt", Server: "mirror1", Time: start},
+		{Client: "10.0.0.2", Size: 2 << 20, URL: "/ubuntu/<b>.iso", Useragent: "curl", Server: "mirror2", Time: start.Add(time.Hour)},
+		{Client: "10.0.1.1", Size: 512, URL: "/debian/c.deb", Useragent: "apt", Server: "mirror1", Time: start.Add(50 * time.Hour)},
+	}
+	for _, item := range items {
+		as.NoError(a.handleLogItem(item))
+	}
+	as.NoError(a.WriteReport(filename, SortBySize))
+
+	data, err := os.ReadFile(filename)
+	as.NoError(err)
+	report := string(data)
+	as.Contains(report, "Logs from 2024-01-01 00:00:00 to 2024-01-03 03:00:00.")
+	as.Contains(report, `<tr><td>mirror2</td><td class="num" data-sort="2097152">2.0 MiB</td><td class="num">1</td></tr>`)
+	as.Less(strings.Index(report, "<td>mirror2</td>"), strings.Index(report, "<td>mirror1</td>"))
+	// One bar for each hour, in two charts
+	as.Equal(2*51, strings.Count(report, "<rect "))
+	as.Contains(report, `>01-02 00:00</text>`)
+	as.Contains(report, "<td>10.0.0.0/24</td>")
+	as.Contains(report, "<td>10.0.1.0/24</td>")
+	as.Contains(report, "/ubuntu/&lt;b&gt;.iso")
+	as.Contains(report, "<td>/debian</td>")
+	as.Contains(report, "<td>/ubuntu</td>")
+
+	a = newTestAnalyzer(t, analyzeConfig)
+	as.Error(a.WriteReport(filename, SortBySize))
+}
+
+func TestReportChartRange(t *testing.T) {
+	as := assert.New(t)
+	loc := reportLoc
+	reportLoc = time.FixedZone("UTC+5:30", 5*3600+1800)
+	t.Cleanup(func() { reportLoc = loc })
+	filename := filepath.Join(t.TempDir(), "report.html")
+	a := newTestAnalyzer(t, func(c *AnalyzerConfig) {
+		c.Analyze = true
+		c.Report = filename
+	})
+
+	start := time.Date(2024, 1, 1, 22, 30, 0, 0, reportLoc)
+	items := []parser.LogItem{
+		{Client: "10.0.0.1", Size: 100, URL: "/a", Time: start},
+		{Client: "10.0.0.1", Size: 100, URL: "/a", Time: start.Add(3 * time.Hour)},
+		// Wrong time
+		{Client: "10.0.0.1", Size: 100, URL: "/a", Time: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, item := range items {
+		as.NoError(a.handleLogItem(item))
+	}
+	as.NoError(a.WriteReport(filename, SortBySize))
+
+	data, err := os.ReadFile(filename)
+	as.NoError(err)
+	report := string(data)
+	as.Contains(report, "Logs from 2024-01-01 22:00:00 to 2024-01-02 02:00:00.")
+	as.Equal(2*4, strings.Count(report, "<rect "))
+	as.Contains(report, `>01-02 00:00</text>`)
+	as.Contains(report, "1 requests at other times are not shown.")
+}
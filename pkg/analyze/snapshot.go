@@ -0,0 +1,388 @@
+package analyze
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/netip"
+	"os"
+	"time"
+	"unique"
+
+	"github.com/taoky/ayano/pkg/sketch"
+)
+
+// Bump when snapshot structs change incompatibly
+const snapshotVersion = 1
+
+// snapshot is the on-disk form of analyzer state, encoded with gob and gzipped.
+type snapshot struct {
+	Version int
+	Created time.Time
+	// Hostname of the machine saving it, to tell hosts apart when merging
+	Host string
+	// Latest time of log lines
+	Latest   time.Time
+	Stats    []snapshotIPStats
+	DirStats map[string]snapshotDirStats
+
+	// Approximate mode only
+	Approx *snapshotApprox
+
+	// Positions of followed files after lines counted, with --state-file only
+	Checkpoints Checkpoints
+
+	// Hourly traffic for report, with --report only
+	Hourly []snapshotHour
+}
+
+type snapshotHour struct {
+	Server string
+	// Local hour of saving host (see localHour)
+	Hour     int64
+	Size     uint64
+	Requests uint64
+}
+
+type snapshotApprox struct {
+	Sizes             *sketch.CountMin
+	Requests          *sketch.CountMin
+	Keys              *sketch.HyperLogLog
+	UntrackedSize     uint64
+	UntrackedRequests uint64
+}
+
+type snapshotIPStats struct {
+	Server string
+	Prefix netip.Prefix
+
+	Size          uint64
+	Requests      uint64
+	LastURL       string
+	DirStats      map[string]DirectoryStats
+	LastSize      uint64
+	FirstSeen     time.Time
+	LastURLUpdate time.Time
+	LastURLAccess time.Time
+	UserAgents    []string
+	UASketch      *sketch.HyperLogLog
+	SizeErr       uint64
+	RequestsErr   uint64
+	FirstAccess   time.Time
+	RateStart     time.Time
+	RateBytes     uint64
+	LastRate      float64
+	PeakRate      float64
+}
+
+type snapshotDirStats struct {
+	Size          uint64
+	Requests      uint64
+	Prefixes      []netip.Prefix
+	IPSketch      *sketch.HyperLogLog
+	LastURLUpdate time.Time
+	LastURLAccess time.Time
+}
+
+func (a *Analyzer) WriteSnapshot(w io.Writer) error {
+	// No line is being handled while both are held
+	a.stateMu.Lock()
+	defer a.stateMu.Unlock()
+	host, _ := os.Hostname()
+	a.mu.Lock()
+	s := snapshot{
+		Version:     snapshotVersion,
+		Created:     time.Now(),
+		Host:        host,
+		Latest:      a.latestTime,
+		Stats:       make([]snapshotIPStats, 0, len(a.stats)),
+		DirStats:    make(map[string]snapshotDirStats, len(a.dirStats)),
+		Checkpoints: a.currentCheckpoints(),
+	}
+	for k, v := range a.stats {
+		item := snapshotIPStats{
+			Server:        k.Server,
+			Prefix:        k.Prefix,
+			Size:          v.Size,
+			Requests:      v.Requests,
+			LastURL:       v.LastURL,
+			LastSize:      v.LastSize,
+			FirstSeen:     v.FirstSeen,
+			LastURLUpdate: v.LastURLUpdate,
+			LastURLAccess: v.LastURLAccess,
+			UASketch:      v.UASketch,
+			SizeErr:       v.SizeErr,
+			RequestsErr:   v.RequestsErr,
+		}
+		if rate, ok := a.rates[k]; ok {
+			item.FirstAccess = rate.FirstAccess
+			item.RateStart = rate.Start
+			item.RateBytes = rate.Bytes
+			item.LastRate = rate.Last
+			item.PeakRate = rate.Peak
+		}
+		if v.DirStats != nil {
+			item.DirStats = make(map[string]DirectoryStats, len(v.DirStats))
+			for dir, stats := range v.DirStats {
+				item.DirStats[dir] = *stats
+			}
+		}
+		for ua := range v.UAStore {
+			item.UserAgents = append(item.UserAgents, ua.Value())
+		}
+		s.Stats = append(s.Stats, item)
+	}
+	for dir, v := range a.dirStats {
+		item := snapshotDirStats{
+			Size:          v.Size,
+			Requests:      v.Requests,
+			IPSketch:      v.IPSketch,
+			LastURLUpdate: v.LastURLUpdate,
+			LastURLAccess: v.LastURLAccess,
+		}
+		for prefix := range v.IPCount {
+			item.Prefixes = append(item.Prefixes, prefix)
+		}
+		s.DirStats[dir] = item
+	}
+	for k, v := range a.hourly {
+		s.Hourly = append(s.Hourly, snapshotHour{Server: k.server, Hour: k.hour, Size: v.Size, Requests: v.Requests})
+	}
+	if x := a.approx; x != nil {
+		s.Approx = &snapshotApprox{
+			Sizes:             x.sizes,
+			Requests:          x.requests,
+			Keys:              &x.keys,
+			UntrackedSize:     x.untrackedSize,
+			UntrackedRequests: x.untrackedRequests,
+		}
+	}
+	// Sketches are shared with analyzer, so encode before unlocking
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	err := gob.NewEncoder(zw).Encode(s)
+	a.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func decodeSnapshot(r io.Reader) (*snapshot, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	defer zr.Close()
+	var s snapshot
+	if err := gob.NewDecoder(zr).Decode(&s); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	if s.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	return &s, nil
+}
+
+// ReadSnapshot loads a snapshot into analyzer. Existing stats are merged with it.
+func (a *Analyzer) ReadSnapshot(r io.Reader) error {
+	s, err := decodeSnapshot(r)
+	if err != nil {
+		return err
+	}
+	return a.loadSnapshot(s, "")
+}
+
+// ReadHostSnapshot is like ReadSnapshot, but also keeps stats of the host separately,
+// with server prefixed by host (see HostServer), except in approximate mode.
+// host defaults to the one saved in snapshot.
+// Directory statistics must be present in snapshot if analyzer has them.
+func (a *Analyzer) ReadHostSnapshot(r io.Reader, host string) error {
+	s, err := decodeSnapshot(r)
+	if err != nil {
+		return err
+	}
+	if a.dirStats != nil && len(s.DirStats) == 0 && len(s.Stats) > 0 {
+		return errors.New("no directory statistics in snapshot (save it with dir-analyze)")
+	}
+	if a.approx != nil {
+		// Error bounds of tracked prefixes could not be kept for each host
+		return a.loadSnapshot(s, "")
+	}
+	if host == "" {
+		host = s.Host
+	}
+	if host == "" {
+		return errors.New("host is not recorded in snapshot")
+	}
+	return a.loadSnapshot(s, host)
+}
+
+// HostServer returns server name used for stats of server on host when merging snapshots.
+func HostServer(host, server string) string {
+	if server == "" {
+		return host
+	}
+	return host + "/" + server
+}
+
+func (a *Analyzer) loadSnapshot(s *snapshot, host string) error {
+	stats := make(map[StatKey]IPStats, len(s.Stats))
+	rates := make(map[StatKey]rateStats, len(s.Stats))
+	for _, item := range s.Stats {
+		v := IPStats{
+			Size:          item.Size,
+			Requests:      item.Requests,
+			LastURL:       item.LastURL,
+			LastSize:      item.LastSize,
+			FirstSeen:     item.FirstSeen,
+			LastURLUpdate: item.LastURLUpdate,
+			LastURLAccess: item.LastURLAccess,
+			UASketch:      item.UASketch,
+			SizeErr:       item.SizeErr,
+			RequestsErr:   item.RequestsErr,
+		}
+		if item.DirStats != nil {
+			v.DirStats = make(map[string]*DirectoryStats, len(item.DirStats))
+			for dir, stats := range item.DirStats {
+				v.DirStats[dir] = &stats
+			}
+		}
+		if v.UASketch == nil {
+			v.UAStore = make(map[UAKeyType]struct{}, len(item.UserAgents))
+			for _, ua := range item.UserAgents {
+				v.UAStore[unique.Make(ua)] = struct{}{}
+			}
+		}
+		key := StatKey{Server: item.Server, Prefix: item.Prefix}
+		stats[key] = v
+		rates[key] = rateStats{
+			FirstAccess: item.FirstAccess,
+			Start:       item.RateStart,
+			Bytes:       item.RateBytes,
+			Last:        item.LastRate,
+			Peak:        item.PeakRate,
+		}
+	}
+	var from *approxStats
+	if s.Approx != nil {
+		if s.Approx.Sizes == nil || s.Approx.Requests == nil || s.Approx.Keys == nil {
+			return errors.New("invalid snapshot: incomplete approximate stats")
+		}
+		from = &approxStats{
+			sizes:             s.Approx.Sizes,
+			requests:          s.Approx.Requests,
+			keys:              *s.Approx.Keys,
+			untrackedSize:     s.Approx.UntrackedSize,
+			untrackedRequests: s.Approx.UntrackedRequests,
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// Positions saved with stats take precedence over state file, which might be saved at another time
+	if a.checkpoints != nil {
+		maps.Copy(a.checkpoints, s.Checkpoints)
+	}
+	if s.Latest.After(a.latestTime) {
+		a.latestTime = s.Latest
+	}
+	a.mergeStats(stats, from)
+	a.mergeRates(rates)
+	if a.recent != nil {
+		a.restoreRecent(stats, s.Latest)
+	}
+	if host != "" {
+		// Copied as stats are modified when merged
+		for k, v := range stats {
+			hostKey := StatKey{Server: HostServer(host, k.Server), Prefix: k.Prefix}
+			a.stats[hostKey] = a.stats[hostKey].MergeWith(IPStats{}.MergeWith(v))
+			if a.rates != nil {
+				a.rates[hostKey] = a.rates[hostKey].mergeWith(rates[k])
+			}
+		}
+	}
+	if a.hourly != nil {
+		for _, item := range s.Hourly {
+			key := hourKey{item.Server, item.Hour}
+			c := a.hourly[key]
+			c.Size += item.Size
+			c.Requests += item.Requests
+			a.hourly[key] = c
+		}
+	}
+	if a.dirStats != nil {
+		for dir, item := range s.DirStats {
+			v := &DirectoryTotalStats{
+				Size:          item.Size,
+				Requests:      item.Requests,
+				IPSketch:      item.IPSketch,
+				LastURLUpdate: item.LastURLUpdate,
+				LastURLAccess: item.LastURLAccess,
+			}
+			if v.IPSketch == nil && a.approx != nil {
+				v.IPSketch = new(sketch.HyperLogLog)
+			}
+			for _, prefix := range item.Prefixes {
+				v.addIP(prefix)
+			}
+			if old, ok := a.dirStats[dir]; ok {
+				old.MergeWith(v)
+			} else {
+				a.dirStats[dir] = v
+			}
+		}
+	}
+	return nil
+}
+
+// SaveSnapshot writes snapshot to Config.Snapshot.
+func (a *Analyzer) SaveSnapshot() error {
+	var buf bytes.Buffer
+	if err := a.WriteSnapshot(&buf); err != nil {
+		return err
+	}
+	return writeFileAtomic(a.Config.Snapshot, buf.Bytes())
+}
+
+// LoadSnapshot reads snapshot from filename, and it's not an error if it does not exist.
+func (a *Analyzer) LoadSnapshot(filename string) error {
+	f, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return a.ReadSnapshot(f)
+}
+
+// SaveSnapshotEvery saves snapshot periodically, and never returns.
+func (a *Analyzer) SaveSnapshotEvery(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := a.SaveSnapshot(); err != nil {
+			a.logger.Printf("save snapshot error: %v", err)
+		}
+	}
+}
+
+// SaveState saves checkpoints and snapshot, if configured.
+func (a *Analyzer) SaveState() error {
+	var errs []error
+	if a.Config.Snapshot != "" {
+		errs = append(errs, a.SaveSnapshot())
+	}
+	if a.Config.StateFile != "" {
+		errs = append(errs, a.SaveCheckpoints())
+	}
+	return errors.Join(errs...)
+}
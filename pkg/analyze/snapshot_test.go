@@ -0,0 +1,164 @@
+package analyze
+
+import (
+	"bytes"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taoky/ayano/pkg/fileiter"
+	"github.com/taoky/ayano/pkg/parser"
+)
+
+func TestSnapshot(t *testing.T) {
+	as := assert.New(t)
+	a := newTestAnalyzer(t, dirAnalyzeConfig)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, client := range []string{"10.0.0.1", "10.0.0.2", "10.0.1.1"} {
+		as.NoError(a.handleLogItem(parser.LogItem{
+			Client:    client,
+			Size:      uint64(100 * (i + 1)),
+			URL:       "/debian/pool/a.deb",
+			Time:      now.Add(time.Duration(i) * time.Minute),
+			Useragent: "apt",
+		}))
+	}
+	key := StatKey{Prefix: netip.MustParsePrefix("10.0.0.0/24")}
+	stats := a.stats[key]
+	stats.LastSize = 42
+	stats.FirstSeen = now
+	a.stats[key] = stats
+
+	var buf bytes.Buffer
+	as.NoError(a.WriteSnapshot(&buf))
+	data := buf.Bytes()
+
+	b := newTestAnalyzer(t, dirAnalyzeConfig)
+	as.NoError(b.ReadSnapshot(bytes.NewReader(data)))
+	as.Equal(a.stats, b.stats)
+	as.Equal(a.dirStats, b.dirStats)
+
+	// Loading again merges
+	as.NoError(b.ReadSnapshot(bytes.NewReader(data)))
+	as.Equal(uint64(600), b.stats[key].Size)
+	as.Equal(uint64(4), b.stats[key].Requests)
+	as.Equal(uint64(1200), b.dirStats["/debian"].Size)
+	as.Len(b.dirStats["/debian"].IPCount, 2)
+
+	as.Error(b.ReadSnapshot(bytes.NewReader([]byte("garbage"))))
+}
+
+func TestSnapshotHourly(t *testing.T) {
+	as := assert.New(t)
+	reportConfig := func(c *AnalyzerConfig) {
+		c.Analyze = true
+		c.Report = filepath.Join(t.TempDir(), "report.html")
+	}
+	a := newTestAnalyzer(t, reportConfig)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		as.NoError(a.handleLogItem(parser.LogItem{
+			Client: "10.0.0.1",
+			Size:   100,
+			URL:    "/a",
+			Time:   now.Add(time.Duration(i) * 40 * time.Minute),
+			Server: "mirror",
+		}))
+	}
+	as.Len(a.hourly, 2)
+
+	var buf bytes.Buffer
+	as.NoError(a.WriteSnapshot(&buf))
+	data := buf.Bytes()
+
+	b := newTestAnalyzer(t, reportConfig)
+	as.NoError(b.ReadSnapshot(bytes.NewReader(data)))
+	as.Equal(a.hourly, b.hourly)
+	as.NoError(b.ReadSnapshot(bytes.NewReader(data)))
+	as.Equal(uint64(4), b.hourly[hourKey{"mirror", localHour(now)}].Requests)
+}
+
+func TestSnapshotCheckpoints(t *testing.T) {
+	as := assert.New(t)
+	dir := t.TempDir()
+	newDaemon := func() *Analyzer {
+		return newTestAnalyzer(t, func(c *AnalyzerConfig) {
+			c.Daemon = true
+			c.Whole = true
+			c.StateFile = filepath.Join(dir, "state.json")
+			c.Snapshot = filepath.Join(dir, "snapshot")
+		})
+	}
+	handle := func(a *Analyzer, iter fileiter.Iterator, n int) {
+		for range n {
+			l, err := nextLine(iter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			as.NoError(a.handleLineAt(l))
+		}
+	}
+
+	const total = 10
+	filename := filepath.Join(dir, "access.log")
+	var content strings.Builder
+	for range total {
+		content.WriteString(`10.0.0.1 - - [01/Jan/2024:00:00:00 +0000] "GET /a HTTP/1.1" 200 100 "-" "curl"` + "\n")
+	}
+	if err := os.WriteFile(filename, []byte(content.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := newDaemon()
+	iter, err := a.OpenTailIterator(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle(a, iter, 3)
+	as.NoError(a.SaveCheckpoints())
+	// Snapshot is saved later than state file, which is now stale
+	handle(a, iter, 4)
+	as.NoError(a.SaveSnapshot())
+
+	b := newDaemon()
+	as.NoError(b.LoadSnapshot(b.Config.Snapshot))
+	iter, err = b.OpenTailIterator(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle(b, iter, total-7)
+	// Continued from where the snapshot was taken, instead of the stale state file
+	key, _ := filepath.Abs(filename)
+	as.EqualValues(content.Len(), b.positions[key].Offset)
+	statKey := StatKey{Prefix: netip.MustParsePrefix("10.0.0.0/24")}
+	as.Equal(uint64(total), b.stats[statKey].Requests)
+	as.Equal(uint64(total*100), b.stats[statKey].Size)
+}
+
+func TestReadHostSnapshot(t *testing.T) {
+	as := assert.New(t)
+	var snapshots [][]byte
+	for _, client := range []string{"10.0.0.1", "10.0.0.2"} {
+		a := newTestAnalyzer(t, analyzeConfig)
+		as.NoError(a.handleLogItem(parser.LogItem{Client: client, Size: 100, URL: "/a"}))
+		var buf bytes.Buffer
+		as.NoError(a.WriteSnapshot(&buf))
+		snapshots = append(snapshots, buf.Bytes())
+	}
+
+	m := newTestAnalyzer(t, analyzeConfig)
+	as.NoError(m.ReadHostSnapshot(bytes.NewReader(snapshots[0]), "mirror1"))
+	as.NoError(m.ReadHostSnapshot(bytes.NewReader(snapshots[1]), "mirror2"))
+	prefix := netip.MustParsePrefix("10.0.0.0/24")
+	as.Equal(uint64(200), m.stats[StatKey{Prefix: prefix}].Size)
+	as.Equal(uint64(100), m.stats[StatKey{Server: "mirror1", Prefix: prefix}].Size)
+	as.Equal(uint64(100), m.stats[StatKey{Server: "mirror2", Prefix: prefix}].Size)
+
+	// Snapshots without directory statistics
+	d := newTestAnalyzer(t, dirAnalyzeConfig)
+	as.Error(d.ReadHostSnapshot(bytes.NewReader(snapshots[0]), "mirror1"))
+}
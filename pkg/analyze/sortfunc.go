@@ -1,6 +1,7 @@
 package analyze
 
 import (
+	"cmp"
 	"fmt"
 	"slices"
 )
@@ -12,6 +13,7 @@ const (
 	SortByRequests   SortByFlag = "requests"
 	SortByDirectory  SortByFlag = "directory"
 	SortByUserAgents SortByFlag = "user-agents"
+	SortByRate       SortByFlag = "rate"
 )
 
 func (s SortByFlag) String() string {
@@ -28,6 +30,8 @@ func (s *SortByFlag) Set(value string) error {
 		*s = SortByDirectory
 	case string(SortByUserAgents), "ua", "uas":
 		*s = SortByUserAgents
+	case "rate":
+		*s = SortByRate
 	default:
 		return fmt.Errorf("must be one of: %v", ListSortFuncs())
 	}
@@ -40,30 +44,40 @@ func (s SortByFlag) Type() string {
 
 type SortFunc func(l, r StatKey) int
 
-var sortFuncs = map[SortByFlag]func(a map[StatKey]IPStats) SortFunc{
-	SortBySize: func(i map[StatKey]IPStats) SortFunc {
+var sortFuncs = map[SortByFlag]func(a *Analyzer) SortFunc{
+	SortBySize: func(a *Analyzer) SortFunc {
+		i := a.stats
 		return func(l, r StatKey) int {
 			return int(i[r].Size - i[l].Size)
 		}
 	},
-	SortByRequests: func(i map[StatKey]IPStats) SortFunc {
+	SortByRequests: func(a *Analyzer) SortFunc {
+		i := a.stats
 		return func(l, r StatKey) int {
 			return int(i[r].Requests - i[l].Requests)
 		}
 	},
-	SortByUserAgents: func(i map[StatKey]IPStats) SortFunc {
+	SortByUserAgents: func(a *Analyzer) SortFunc {
+		i := a.stats
 		return func(l, r StatKey) int {
-			return len(i[r].UAStore) - len(i[l].UAStore)
+			ls, _ := i[l].UserAgents()
+			rs, _ := i[r].UserAgents()
+			return rs - ls
+		}
+	},
+	SortByRate: func(a *Analyzer) SortFunc {
+		return func(l, r StatKey) int {
+			return cmp.Compare(a.rate(r), a.rate(l))
 		}
 	},
 }
 
-func GetSortFunc(name SortByFlag, i map[StatKey]IPStats) SortFunc {
+func GetSortFunc(name SortByFlag, a *Analyzer) SortFunc {
 	fn, ok := sortFuncs[name]
 	if !ok {
 		return nil
 	}
-	return fn(i)
+	return fn(a)
 }
 
 func ListSortFuncs() []SortByFlag {
@@ -5,6 +5,7 @@ import (
 	"net/netip"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -168,3 +169,11 @@ func HumanizeAgo(d time.Duration) string {
 	hours := int(d.Hours()) - days*24
 	return fmt.Sprintf("%dd%2dh ago", days, hours)
 }
+
+// formatCount formats a count, prefixed with "~" if it's estimated.
+func formatCount(n int, estimated bool) string {
+	if estimated {
+		return "~" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,222 @@
+package analyze
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+// Number of slots in a window, or ticks in a half-life
+const recentSlots = 10
+
+// Decayed entries smaller than this many requests are evicted
+const decayEvictRequests = 0.01
+
+type counter struct {
+	Size     uint64
+	Requests uint64
+}
+
+type windowSlot struct {
+	start  time.Time
+	counts map[StatKey]counter
+}
+
+type decayedCounter struct {
+	size     float64
+	requests float64
+	// Time of last decay
+	updated time.Time
+}
+
+// recentStats makes IPStats Size and Requests reflect only recent traffic,
+// either in a sliding window, or with exponential decay.
+// Times here are from log items, so that replaying old logs also works.
+type recentStats struct {
+	window   time.Duration
+	halfLife time.Duration
+	slot     time.Duration
+
+	// Start of current slot
+	current time.Time
+	// Latest log time seen
+	latest time.Time
+
+	// Window only: contributions in each slot, oldest first
+	slots []windowSlot
+	// Decay only
+	decayed map[StatKey]*decayedCounter
+}
+
+func newRecentStats(window, halfLife time.Duration) *recentStats {
+	r := &recentStats{window: window, halfLife: halfLife}
+	if window > 0 {
+		r.slot = window / recentSlots
+	} else {
+		r.slot = halfLife / recentSlots
+		r.decayed = make(map[StatKey]*decayedCounter)
+	}
+	return r
+}
+
+// span is how long traffic is considered recent
+func (r *recentStats) span() time.Duration {
+	if r.window > 0 {
+		return r.window
+	}
+	return r.halfLife
+}
+
+func (r *recentStats) decayFactor(d time.Duration) float64 {
+	return math.Exp2(-d.Seconds() / r.halfLife.Seconds())
+}
+
+// isStale reports whether t is too old to be counted.
+func (r *recentStats) isStale(t time.Time) bool {
+	return r.window > 0 && !t.Truncate(r.slot).Add(r.window).After(r.current)
+}
+
+// add records a log item already counted in a.stats[key]
+func (r *recentStats) add(key StatKey, size uint64, t time.Time) {
+	r.addCounter(key, counter{Size: size, Requests: 1}, t)
+}
+
+// addCounter records traffic at t already counted in a.stats[key]
+func (r *recentStats) addCounter(key StatKey, c counter, t time.Time) {
+	if r.window > 0 {
+		// Lines slightly out of order go to their own slot if possible, or the newest one
+		start := t.Truncate(r.slot)
+		i := len(r.slots) - 1
+		for i > 0 && r.slots[i].start.After(start) {
+			i--
+		}
+		sc := r.slots[i].counts[key]
+		sc.Size += c.Size
+		sc.Requests += c.Requests
+		r.slots[i].counts[key] = sc
+		return
+	}
+	d, ok := r.decayed[key]
+	if !ok {
+		d = &decayedCounter{updated: t}
+		r.decayed[key] = d
+	}
+	if t.After(d.updated) {
+		f := r.decayFactor(t.Sub(d.updated))
+		d.size *= f
+		d.requests *= f
+		d.updated = t
+	}
+	d.size += float64(c.Size)
+	d.requests += float64(c.Requests)
+}
+
+// restoreRecent makes stats loaded from snapshot expire or decay.
+// In a window, all traffic of a key is considered at its last access.
+// Decayed stats are up to date at latest, the latest log time when snapshot was saved.
+func (a *Analyzer) restoreRecent(stats map[StatKey]IPStats, latest time.Time) {
+	lastAccess := func(key StatKey) time.Time {
+		if t := stats[key].LastURLAccess; a.recent.window > 0 && !t.IsZero() {
+			return t
+		}
+		return latest
+	}
+	keys := slices.Collect(maps.Keys(stats))
+	slices.SortFunc(keys, func(l, r StatKey) int {
+		return lastAccess(l).Compare(lastAccess(r))
+	})
+	for _, key := range keys {
+		v := stats[key]
+		c := counter{Size: v.Size, Requests: v.Requests}
+		t := lastAccess(key)
+		a.advanceRecent(t)
+		if a.recent.isStale(t) {
+			a.subtractStats(key, c)
+			continue
+		}
+		a.recent.addCounter(key, c, t)
+	}
+}
+
+// advanceRecent moves current slot to contain t, and expires old traffic from a.stats.
+func (a *Analyzer) advanceRecent(t time.Time) {
+	r := a.recent
+	if t.After(r.latest) {
+		r.latest = t
+	}
+	slotStart := t.Truncate(r.slot)
+	if !r.current.IsZero() && !slotStart.After(r.current) {
+		return
+	}
+	r.current = slotStart
+
+	if r.window > 0 {
+		r.slots = append(r.slots, windowSlot{start: slotStart, counts: make(map[StatKey]counter)})
+		expired := 0
+		for expired < len(r.slots) && !r.slots[expired].start.Add(r.window).After(slotStart) {
+			for key, c := range r.slots[expired].counts {
+				a.subtractStats(key, c)
+			}
+			expired++
+		}
+		r.slots = r.slots[expired:]
+		return
+	}
+
+	for key, d := range r.decayed {
+		f := r.decayFactor(t.Sub(d.updated))
+		d.size *= f
+		d.requests *= f
+		d.updated = t
+		if d.requests < decayEvictRequests {
+			delete(r.decayed, key)
+			a.deleteStats(key)
+			continue
+		}
+		stats, ok := a.stats[key]
+		if !ok {
+			continue
+		}
+		stats.Size = uint64(math.Round(d.size))
+		stats.Requests = uint64(math.Round(d.requests))
+		a.stats[key] = a.adjustLastSize(stats)
+	}
+}
+
+// advanceRecentToNow expires old traffic by wall clock, when analyzing live logs
+// and there might be no new lines for a while.
+func (a *Analyzer) advanceRecentToNow() {
+	now := time.Now()
+	r := a.recent
+	if r.latest.IsZero() || r.latest.Before(now.Add(-r.span())) {
+		// Not live (replaying old logs)
+		return
+	}
+	a.advanceRecent(now)
+}
+
+func (a *Analyzer) subtractStats(key StatKey, c counter) {
+	stats, ok := a.stats[key]
+	if !ok {
+		return
+	}
+	if stats.Requests <= c.Requests {
+		// Evict to bound memory
+		a.deleteStats(key)
+		return
+	}
+	stats.Requests -= c.Requests
+	stats.Size -= min(stats.Size, c.Size)
+	a.stats[key] = a.adjustLastSize(stats)
+}
+
+// adjustLastSize keeps daemon mode printing when Size crosses multiples of PrintDelta,
+// after Size decreases.
+func (a *Analyzer) adjustLastSize(stats IPStats) IPStats {
+	if stats.LastSize > stats.Size {
+		delta := max(uint64(a.Config.PrintDelta), 1)
+		stats.LastSize = stats.Size / delta * delta
+	}
+	return stats
+}
@@ -0,0 +1,118 @@
+package analyze
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taoky/ayano/pkg/parser"
+)
+
+func newRecentTestAnalyzer(t *testing.T, window, halfLife time.Duration) *Analyzer {
+	return newTestAnalyzer(t, func(c *AnalyzerConfig) {
+		c.Daemon = true
+		c.PrintDelta = 1000
+		c.Window = window
+		c.HalfLife = halfLife
+	})
+}
+
+func TestWindow(t *testing.T) {
+	as := assert.New(t)
+	a := newRecentTestAnalyzer(t, 10*time.Minute, 0)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	add := func(client string, minutes float64) {
+		as.NoError(a.handleLogItem(parser.LogItem{
+			Client: client,
+			Size:   100,
+			URL:    "/a",
+			Time:   start.Add(time.Duration(minutes * float64(time.Minute))),
+		}))
+	}
+	key1 := StatKey{Prefix: netip.MustParsePrefix("10.0.1.0/24")}
+	key2 := StatKey{Prefix: netip.MustParsePrefix("10.0.2.0/24")}
+
+	add("10.0.1.1", 0)
+	add("10.0.1.1", 5)
+	add("10.0.2.1", 5)
+	as.Equal(uint64(200), a.stats[key1].Size)
+	as.Equal(uint64(2), a.stats[key1].Requests)
+
+	// First request of key1 falls out of window
+	add("10.0.2.1", 10.5)
+	as.Equal(uint64(100), a.stats[key1].Size)
+	as.Equal(uint64(1), a.stats[key1].Requests)
+	as.Equal(uint64(200), a.stats[key2].Size)
+
+	// Too old to be counted
+	add("10.0.1.1", 0.5)
+	as.Equal(uint64(1), a.stats[key1].Requests)
+
+	// key1 is evicted
+	add("10.0.2.1", 16)
+	_, ok := a.stats[key1]
+	as.False(ok)
+	as.Equal(uint64(200), a.stats[key2].Size)
+}
+
+func TestHalfLife(t *testing.T) {
+	as := assert.New(t)
+	a := newRecentTestAnalyzer(t, 0, 10*time.Minute)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := StatKey{Prefix: netip.MustParsePrefix("10.0.1.0/24")}
+	as.NoError(a.handleLogItem(parser.LogItem{Client: "10.0.1.1", Size: 4000, Time: start}))
+	as.Equal(uint64(4000), a.stats[key].Size)
+	as.Equal(uint64(4000), a.stats[key].LastSize)
+
+	as.NoError(a.handleLogItem(parser.LogItem{Client: "10.0.2.1", Size: 1, Time: start.Add(10 * time.Minute)}))
+	as.Equal(uint64(2000), a.stats[key].Size)
+	as.Equal(uint64(2000), a.stats[key].LastSize)
+
+	as.NoError(a.handleLogItem(parser.LogItem{Client: "10.0.2.1", Size: 1, Time: start.Add(20 * time.Minute)}))
+	as.Equal(uint64(1000), a.stats[key].Size)
+
+	// Evicted after decaying enough
+	as.NoError(a.handleLogItem(parser.LogItem{Client: "10.0.2.1", Size: 1, Time: start.Add(100 * time.Minute)}))
+	_, ok := a.stats[key]
+	as.False(ok)
+}
+
+func TestRecentSnapshot(t *testing.T) {
+	as := assert.New(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	key1 := StatKey{Prefix: netip.MustParsePrefix("10.0.1.0/24")}
+	key2 := StatKey{Prefix: netip.MustParsePrefix("10.0.2.0/24")}
+	for _, tc := range []struct {
+		window, halfLife time.Duration
+	}{{10 * time.Minute, 0}, {0, 10 * time.Minute}} {
+		a := newRecentTestAnalyzer(t, tc.window, tc.halfLife)
+		as.NoError(a.handleLogItem(parser.LogItem{Client: "10.0.1.1", Size: 4000, Time: start}))
+		as.NoError(a.handleLogItem(parser.LogItem{Client: "10.0.2.1", Size: 4000, Time: start.Add(5 * time.Minute)}))
+		var buf bytes.Buffer
+		as.NoError(a.WriteSnapshot(&buf))
+
+		b := newRecentTestAnalyzer(t, tc.window, tc.halfLife)
+		as.NoError(b.ReadSnapshot(&buf))
+		as.Equal(a.stats[key1].Size, b.stats[key1].Size)
+		// Restored stats expire or decay as new traffic comes
+		as.NoError(b.handleLogItem(parser.LogItem{Client: "10.0.2.1", Size: 1, Time: start.Add(10 * time.Minute)}))
+		if tc.window > 0 {
+			_, ok := b.stats[key1]
+			as.False(ok)
+			as.Equal(uint64(4001), b.stats[key2].Size)
+		} else {
+			as.Equal(uint64(2000), b.stats[key1].Size)
+		}
+	}
+}
+
+func TestRecentFlagsExclusive(t *testing.T) {
+	c := DefaultConfig()
+	c.Parser = "nginx-combined"
+	c.Window = time.Minute
+	c.HalfLife = time.Minute
+	_, err := NewAnalyzer(c)
+	assert.Error(t, err)
+}
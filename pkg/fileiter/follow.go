@@ -0,0 +1,158 @@
+package fileiter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+const pollInterval = 250 * time.Millisecond
+
+// Position identifies a byte offset in a file by its device and inode,
+// so it's still valid after the file is renamed by log rotation.
+type Position struct {
+	Dev    uint64 `json:"dev"`
+	Ino    uint64 `json:"ino"`
+	Offset int64  `json:"offset"`
+}
+
+func (p Position) SameFile(other Position) bool {
+	return p.Dev == other.Dev && p.Ino == other.Ino
+}
+
+// FilePosition returns position at the beginning of file described by fi.
+func FilePosition(fi os.FileInfo) Position {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return Position{}
+	}
+	return Position{Dev: uint64(st.Dev), Ino: uint64(st.Ino)}
+}
+
+// Follower follows a file like "tail -F", and keeps track of position of lines read.
+type Follower struct {
+	filename string
+
+	f   *os.File
+	r   *bufio.Reader
+	pos Position
+	// Incomplete line read
+	buf []byte
+	// File to switch to after current file is read to the end
+	pending string
+}
+
+// NewFollower starts following filename from offset in file at pos.
+// If pos refers to another file (which has been rotated), it should be given as rotated,
+// and would be read to the end before switching to filename.
+func NewFollower(filename string, pos Position, rotated string) (*Follower, error) {
+	fl := &Follower{filename: filename}
+	name := filename
+	if rotated != "" {
+		name = rotated
+		fl.pending = filename
+	}
+	if err := fl.open(name, pos.Offset); err != nil {
+		return nil, err
+	}
+	return fl, nil
+}
+
+func (fl *Follower) open(name string, offset int64) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if offset > fi.Size() {
+		// Truncated
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	if fl.f != nil {
+		fl.f.Close()
+	}
+	fl.f = f
+	fl.r = bufio.NewReaderSize(f, 1024*1024)
+	fl.pos = FilePosition(fi)
+	fl.pos.Offset = offset
+	fl.buf = nil
+	return nil
+}
+
+// Tell returns position after the last line returned by Next.
+// It must be called from the goroutine calling Next.
+func (fl *Follower) Tell() Position {
+	return fl.pos
+}
+
+func (fl *Follower) Next() ([]byte, error) {
+	for {
+		line, err := fl.r.ReadBytes('\n')
+		fl.buf = append(fl.buf, line...)
+		if err == nil {
+			return fl.takeLine(), nil
+		}
+		if !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+
+		if fl.pending != "" {
+			// Old file has been read to the end
+			if len(fl.buf) > 0 {
+				// Incomplete last line
+				return fl.takeLine(), nil
+			}
+			name := fl.pending
+			fl.pending = ""
+			if err := fl.open(name, 0); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		changed, err := fl.checkChange()
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			time.Sleep(pollInterval)
+		}
+	}
+}
+
+func (fl *Follower) takeLine() []byte {
+	line := fl.buf
+	fl.buf = nil
+	fl.pos.Offset += int64(len(line))
+	return bytes.TrimRight(line, "\r\n")
+}
+
+// checkChange checks if the file is rotated or truncated at EOF.
+func (fl *Follower) checkChange() (bool, error) {
+	fi, err := os.Stat(fl.filename)
+	if err != nil {
+		// Renamed and not created yet
+		return false, nil
+	}
+	if !FilePosition(fi).SameFile(fl.pos) {
+		// Read the old file again to the end before switching,
+		// as it might be written after last read.
+		fl.pending = fl.filename
+		return true, nil
+	}
+	if fi.Size() < fl.pos.Offset+int64(len(fl.buf)) {
+		return true, fl.open(fl.filename, 0)
+	}
+	return false, nil
+}
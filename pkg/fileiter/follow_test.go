@@ -0,0 +1,83 @@
+package fileiter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func appendFileErr(filename, content string) error {
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(content)
+	return err
+}
+
+func appendFile(t *testing.T, filename, content string) {
+	if err := appendFileErr(filename, content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func nextString(t *testing.T, iter Iterator) string {
+	line, err := iter.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(line)
+}
+
+func TestFollower(t *testing.T) {
+	as := assert.New(t)
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "access.log")
+	appendFile(t, filename, "a\nb\n")
+
+	fl, err := NewFollower(filename, Position{}, "")
+	as.NoError(err)
+	as.Equal("a", nextString(t, fl))
+	as.Equal(int64(2), fl.Tell().Offset)
+	as.Equal("b", nextString(t, fl))
+	as.Equal(int64(4), fl.Tell().Offset)
+	oldPos := fl.Tell()
+
+	// Incomplete line is not returned until completed
+	appendFile(t, filename, "c")
+	errCh := make(chan error)
+	go func() {
+		errCh <- appendFileErr(filename, "c\n")
+	}()
+	as.Equal("cc", nextString(t, fl))
+	as.NoError(<-errCh)
+	as.Equal(int64(7), fl.Tell().Offset)
+
+	// Rotation: old file is finished before switching
+	rotated := filename + ".1"
+	as.NoError(os.Rename(filename, rotated))
+	appendFile(t, rotated, "d\n")
+	appendFile(t, filename, "e\n")
+	as.Equal("d", nextString(t, fl))
+	as.True(oldPos.SameFile(fl.Tell()))
+	as.Equal(int64(9), fl.Tell().Offset)
+	as.Equal("e", nextString(t, fl))
+	as.False(oldPos.SameFile(fl.Tell()))
+	as.Equal(int64(2), fl.Tell().Offset)
+
+	// Resuming from rotated file, with the last line not consumed yet
+	fl, err = NewFollower(filename, oldPos, rotated)
+	as.NoError(err)
+	as.Equal("cc", nextString(t, fl))
+	as.Equal("d", nextString(t, fl))
+	as.Equal("e", nextString(t, fl))
+	appendFile(t, filename, "f\n")
+	as.Equal("f", nextString(t, fl))
+	fi, err := os.Stat(filename)
+	as.NoError(err)
+	as.True(FilePosition(fi).SameFile(fl.Tell()))
+	as.Equal(int64(4), fl.Tell().Offset)
+}
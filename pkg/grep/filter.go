@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/netip"
+	"slices"
 	"strings"
 	"time"
 
@@ -20,6 +21,8 @@ type Filter struct {
 	TimeTo      time.Time
 	Threshold   util.SizeFlag
 	Server      string
+	Statuses    []int
+	Methods     []string
 }
 
 var timeFormats = []string{
@@ -44,11 +47,13 @@ func (f *Filter) InstallFlags(flags *pflag.FlagSet) {
 	flags.TimeVar(&f.TimeFrom, "time-from", f.TimeFrom, timeFormats, "Start time to filter (inclusive). Default value (zero) means no limit")
 	flags.TimeVar(&f.TimeTo, "time-to", f.TimeTo, timeFormats, "End time to filter (inclusive). Default value (zero) means no limit")
 	flags.VarP(&f.Threshold, "threshold", "t", "Threshold size for request (only requests at least this large will be counted)")
-	flags.StringVarP(&f.Server, "server", "s", f.Server, "Server IP to filter (nginx-json only)")
+	flags.StringVarP(&f.Server, "server", "s", f.Server, "Server to filter (only for formats with server field)")
+	flags.IntSliceVar(&f.Statuses, "status", f.Statuses, "HTTP status code to filter (can be specified multiple times)")
+	flags.StringArrayVar(&f.Methods, "method", f.Methods, "HTTP request method to filter (can be specified multiple times)")
 }
 
 func (f *Filter) IsEmpty() bool {
-	return len(f.Prefixes) == 0 && len(f.UrlContains) == 0 && len(f.UAContains) == 0 && f.TimeFrom.IsZero() && f.TimeTo.IsZero() && f.Threshold == 0 && f.Server == ""
+	return len(f.Prefixes) == 0 && len(f.UrlContains) == 0 && len(f.UAContains) == 0 && f.TimeFrom.IsZero() && f.TimeTo.IsZero() && f.Threshold == 0 && f.Server == "" && len(f.Statuses) == 0 && len(f.Methods) == 0
 }
 
 var (
@@ -59,9 +64,24 @@ var (
 	ErrTimeNoMatch   = errors.New("time does not match")
 	ErrSizeTooSmall  = errors.New("size below threshold")
 	ErrServerNoMatch = errors.New("server does not match")
+	ErrStatusNoMatch = errors.New("status does not match")
+	ErrMethodNoMatch = errors.New("method does not match")
 )
 
 func (f *Filter) Match(item parser.LogItem) error {
+	if err := f.MatchAnySize(item); err != nil {
+		return err
+	}
+	if f.Threshold > 0 {
+		if item.Size < uint64(f.Threshold) {
+			return ErrSizeTooSmall
+		}
+	}
+	return nil
+}
+
+// MatchAnySize is Match without the size threshold.
+func (f *Filter) MatchAnySize(item parser.LogItem) error {
 	if len(f.Prefixes) > 0 {
 		ip, err := netip.ParseAddr(item.Client)
 		if err != nil {
@@ -112,15 +132,27 @@ func (f *Filter) Match(item parser.LogItem) error {
 			return ErrTimeNoMatch
 		}
 	}
-	if f.Threshold > 0 {
-		if item.Size < uint64(f.Threshold) {
-			return ErrSizeTooSmall
-		}
-	}
 	if f.Server != "" {
 		if item.Server != f.Server {
 			return ErrServerNoMatch
 		}
 	}
+	if len(f.Statuses) > 0 {
+		if !slices.Contains(f.Statuses, item.Status) {
+			return ErrStatusNoMatch
+		}
+	}
+	if len(f.Methods) > 0 {
+		methodMatch := false
+		for _, method := range f.Methods {
+			if strings.EqualFold(item.Method, method) {
+				methodMatch = true
+				break
+			}
+		}
+		if !methodMatch {
+			return ErrMethodNoMatch
+		}
+	}
 	return nil
 }
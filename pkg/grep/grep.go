@@ -10,26 +10,30 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/taoky/ayano/pkg/fileiter"
 	"github.com/taoky/ayano/pkg/parser"
+	"github.com/taoky/ayano/pkg/timeseek"
 	"github.com/taoky/ayano/pkg/util"
 )
 
 type Grepper struct {
-	f   *Filter
-	p   parser.Parser
-	out io.Writer
+	f         *Filter
+	p         parser.Parser
+	out       io.Writer
+	extDecomp bool
 }
 
 type GrepperConfig struct {
 	f *Filter
 
-	Parser string
-	Output string
+	Parser     string
+	ParserOpts []string
+	Output     string
+	ExtDecomp  bool
 }
 
 func DefaultConfig() GrepperConfig {
 	return GrepperConfig{
 		f:      &Filter{},
-		Parser: "nginx-json",
+		Parser: parser.AutoParser,
 	}
 }
 
@@ -37,11 +41,17 @@ func (c *GrepperConfig) InstallFlags(flags *pflag.FlagSet) {
 	c.f.InstallFlags(flags)
 
 	flags.StringVarP(&c.Output, "output", "o", c.Output, "Output file name")
+	flags.BoolVar(&c.ExtDecomp, "external-decompress", c.ExtDecomp, "Decompress logs with external commands (gzip, xz, etc.), which might be faster")
 	flags.StringVarP(&c.Parser, "parser", "p", c.Parser, "Log parser (see \"ayano list parsers\")")
+	flags.StringArrayVar(&c.ParserOpts, "parser-opt", c.ParserOpts, "Parser option in key=value form (can be specified multiple times)")
 }
 
 func New(c GrepperConfig, w io.Writer) (*Grepper, error) {
-	p, err := parser.GetParser(c.Parser)
+	parserOpts, err := parser.ParseOptions(c.ParserOpts)
+	if err != nil {
+		return nil, err
+	}
+	p, err := parser.GetParserWithOptions(c.Parser, parserOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -54,9 +64,10 @@ func New(c GrepperConfig, w io.Writer) (*Grepper, error) {
 	}
 
 	g := &Grepper{
-		f:   c.f,
-		p:   p,
-		out: w,
+		f:         c.f,
+		p:         p,
+		out:       w,
+		extDecomp: c.ExtDecomp,
 	}
 	return g, nil
 }
@@ -65,6 +76,10 @@ func (g *Grepper) IsEmpty() bool {
 	return g.f.IsEmpty()
 }
 
+func (c *GrepperConfig) IsEmpty() bool {
+	return c.f.IsEmpty()
+}
+
 func (g *Grepper) RunLoop(iter fileiter.Iterator) error {
 	for {
 		line, err := iter.Next()
@@ -82,12 +97,16 @@ func (g *Grepper) RunLoop(iter fileiter.Iterator) error {
 }
 
 func (g *Grepper) GrepFile(filename string) error {
-	f, err := util.OpenFile(filename)
+	openFile := util.OpenFile
+	if g.extDecomp {
+		openFile = util.OpenFileWithCommand
+	}
+	f, err := timeseek.OpenFrom(filename, openFile, g.p, g.f.TimeFrom)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	return g.RunLoop(fileiter.NewWithScanner(f))
+	return g.RunLoop(timeseek.Until(fileiter.NewWithScanner(f), g.p, g.f.TimeTo))
 }
 
 func (g *Grepper) handleLine(line []byte) error {
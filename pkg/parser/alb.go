@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+func init() {
+	newFunc := func() Parser { return ParserFunc(ParseALB) }
+	RegisterParser(ParserMeta{
+		Name:        "aws-alb",
+		Description: "AWS Application Load Balancer access log format",
+		F:           newFunc,
+	})
+	RegisterParser(ParserMeta{
+		Name:        "alb",
+		Description: "An alias for `aws-alb`",
+		Hidden:      true,
+		F:           newFunc,
+	})
+}
+
+// ALB logs full URL in request line, here only path (and query) is kept
+func stripURLSchemeHost(url []byte) []byte {
+	schemeIdx := bytes.Index(url, []byte("://"))
+	if schemeIdx == -1 {
+		return url
+	}
+	rest := url[schemeIdx+3:]
+	slashIdx := bytes.IndexByte(rest, '/')
+	if slashIdx == -1 {
+		return []byte{'/'}
+	}
+	return rest[slashIdx:]
+}
+
+// ParseALB parses ALB access logs, using load balancer name as server.
+func ParseALB(line []byte) (logItem LogItem, err error) {
+	fields, err := splitFields(line)
+	if err != nil {
+		return logItem, err
+	}
+	if len(fields) < 14 {
+		return logItem, fmt.Errorf("invalid format: expected at least 14 fields, got %d", len(fields))
+	}
+
+	logItem.Time, err = time.Parse(time.RFC3339Nano, string(fields[1]))
+	if err != nil {
+		return logItem, fmt.Errorf("invalid time: %w", err)
+	}
+	logItem.Server = string(fields[2])
+	client, _, err := splitHostPort(fields[3])
+	if err != nil {
+		return logItem, fmt.Errorf("invalid client %s: %w", fields[3], err)
+	}
+	logItem.Client = client.String()
+
+	// request, target and response processing time, -1 if not available
+	var total float64
+	for _, f := range fields[5:8] {
+		secs, err := strconv.ParseFloat(string(f), 64)
+		if err != nil || secs < 0 {
+			total = -1
+			break
+		}
+		total += secs
+	}
+	if total >= 0 {
+		logItem.RequestTime = secondsToDuration(total)
+	}
+
+	// "-" when the connection is closed without response
+	logItem.Status, _ = strconv.Atoi(string(fields[8]))
+	logItem.Size, err = parseCLFSize(fields[11])
+	if err != nil {
+		return logItem, fmt.Errorf("invalid size %s: %w", fields[11], err)
+	}
+
+	method, url, protocol := splitRequestLine(fields[12])
+	logItem.Method = string(method)
+	logItem.URL = string(stripURLSchemeHost(url))
+	logItem.Protocol = string(protocol)
+	logItem.Useragent = string(fields[13])
+	if len(fields) > 18 {
+		logItem.Host = string(fields[18])
+	}
+	return
+}
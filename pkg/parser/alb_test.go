@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestALBParser(t *testing.T) {
+	as := assert.New(t)
+	p, err := GetParser("aws-alb")
+	if !as.NoError(err) {
+		return
+	}
+	line := `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 200 200 0 57 "GET https://www.example.com:443/path/file?x=1 HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012" 1 2018-07-02T22:22:48.364000Z "authenticate,forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`
+	log, err := p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.Equal("app/my-loadbalancer/50dc6c495c0c9188", log.Server)
+		as.Equal("192.168.131.39", log.Client)
+		as.Equal("/path/file?x=1", log.URL)
+		as.EqualValues(57, log.Size)
+		as.Equal(200, log.Status)
+		as.Equal("GET", log.Method)
+		as.Equal("HTTP/1.1", log.Protocol)
+		as.Equal("curl/7.46.0", log.Useragent)
+		as.Equal("www.example.com", log.Host)
+		as.Equal(171*time.Millisecond, log.RequestTime)
+		as.WithinDuration(time.Date(2018, 7, 2, 22, 23, 0, 186641000, time.UTC), log.Time, 0)
+	}
+
+	line = `http 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 - -1 -1 -1 460 - 34 0 "GET http://www.example.com:80 HTTP/1.1" "curl/7.46.0" - - - "-" "-" "-" - 2018-07-02T22:22:48.364000Z "-" "-" "-" "-" "-" "-" "-"`
+	log, err = p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.Equal("/", log.URL)
+		as.Equal(460, log.Status)
+		as.Equal(time.Duration(0), log.RequestTime)
+	}
+}
@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+func init() {
+	RegisterParser(ParserMeta{
+		Name:        "apache-common",
+		Description: "For Apache's `common` format",
+		F:           func() Parser { return ParserFunc(ParseApacheCommon) },
+	})
+	RegisterParser(ParserMeta{
+		Name:        "apache-vhost-combined",
+		Description: "For Apache's `vhost_combined` format",
+		F:           func() Parser { return ParserFunc(ParseApacheVhostCombined) },
+	})
+	RegisterParser(ParserMeta{
+		Name:        "apache-combinedio",
+		Description: "For Apache's `combinedio` format (mod_logio)",
+		F:           func() Parser { return ParserFunc(ParseApacheCombinedIO) },
+	})
+}
+
+// Apache writes "-" instead of 0 for %b
+func parseCLFSize(s []byte) (uint64, error) {
+	if string(s) == "-" {
+		return 0, nil
+	}
+	return strconv.ParseUint(string(s), 10, 64)
+}
+
+// parseCommonFields fills logItem with `%h %l %u %t "%r" %>s %b`, which begins all CLF-like formats
+func parseCommonFields(fields [][]byte, logItem *LogItem) (err error) {
+	logItem.Client = string(fields[0])
+	logItem.Time = clfDateParse(fields[3])
+
+	method, url, protocol := splitRequestLine(fields[4])
+	logItem.Method = string(method)
+	logItem.URL = string(url)
+	logItem.Protocol = string(protocol)
+
+	logItem.Status, err = strconv.Atoi(string(fields[5]))
+	if err != nil {
+		return fmt.Errorf("invalid status %s: %w", fields[5], err)
+	}
+	logItem.Size, err = parseCLFSize(fields[6])
+	if err != nil {
+		return fmt.Errorf("invalid size %s: %w", fields[6], err)
+	}
+	return nil
+}
+
+func ParseApacheCommon(line []byte) (logItem LogItem, err error) {
+	fields, err := splitFields(line)
+	if err != nil {
+		return logItem, err
+	}
+	if len(fields) != 7 {
+		return logItem, fmt.Errorf("invalid format: expected 7 fields, got %d", len(fields))
+	}
+	err = parseCommonFields(fields, &logItem)
+	return
+}
+
+func ParseApacheVhostCombined(line []byte) (logItem LogItem, err error) {
+	fields, err := splitFields(line)
+	if err != nil {
+		return logItem, err
+	}
+	if len(fields) != 10 {
+		return logItem, fmt.Errorf("invalid format: expected 10 fields, got %d", len(fields))
+	}
+	// %v:%p
+	vhost := fields[0]
+	if colonIdx := bytes.LastIndexByte(vhost, ':'); colonIdx != -1 {
+		vhost = vhost[:colonIdx]
+	}
+	logItem.Server = string(vhost)
+	logItem.Host = logItem.Server
+
+	// %O instead of %b here, but "-" is never written for it
+	if err = parseCommonFields(fields[1:], &logItem); err != nil {
+		return
+	}
+	logItem.Referer = string(fields[8])
+	logItem.Useragent = string(fields[9])
+	return
+}
+
+func ParseApacheCombinedIO(line []byte) (logItem LogItem, err error) {
+	fields, err := splitFields(line)
+	if err != nil {
+		return logItem, err
+	}
+	if len(fields) != 11 {
+		return logItem, fmt.Errorf("invalid format: expected 11 fields, got %d", len(fields))
+	}
+	if err = parseCommonFields(fields, &logItem); err != nil {
+		return
+	}
+	logItem.Referer = string(fields[7])
+	logItem.Useragent = string(fields[8])
+	// Use %O (bytes sent, including headers) rather than %b
+	logItem.Size, err = strconv.ParseUint(string(fields[10]), 10, 64)
+	if err != nil {
+		return logItem, fmt.Errorf("invalid size %s: %w", fields[10], err)
+	}
+	return
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApacheCommonParser(t *testing.T) {
+	as := assert.New(t)
+	p := ParserFunc(ParseApacheCommon)
+	line := `127.0.0.1 user-identifier frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`
+	log, err := p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.Equal("127.0.0.1", log.Client)
+		as.Equal("/apache_pb.gif", log.URL)
+		as.EqualValues(2326, log.Size)
+		as.Equal(200, log.Status)
+		as.Equal("GET", log.Method)
+		as.Equal("HTTP/1.0", log.Protocol)
+		expectedTime := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*60*60))
+		as.WithinDuration(expectedTime, log.Time, 0)
+	}
+
+	line = `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "HEAD / HTTP/1.0" 304 -`
+	log, err = p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.EqualValues(0, log.Size)
+		as.Equal(304, log.Status)
+	}
+}
+
+func TestApacheVhostCombinedParser(t *testing.T) {
+	as := assert.New(t)
+	p := ParserFunc(ParseApacheVhostCombined)
+	line := `mirrors.example.com:443 2001:db8::1 - - [10/Oct/2000:13:55:36 -0700] "GET /debian/ls-lR.gz HTTP/1.1" 200 12345 "https://example.com/" "Debian APT-HTTP/1.3 (2.6.1)"`
+	log, err := p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.Equal("mirrors.example.com", log.Server)
+		as.Equal("mirrors.example.com", log.Host)
+		as.Equal("2001:db8::1", log.Client)
+		as.Equal("/debian/ls-lR.gz", log.URL)
+		as.EqualValues(12345, log.Size)
+		as.Equal("https://example.com/", log.Referer)
+		as.Equal("Debian APT-HTTP/1.3 (2.6.1)", log.Useragent)
+	}
+}
+
+func TestApacheCombinedIOParser(t *testing.T) {
+	as := assert.New(t)
+	p := ParserFunc(ParseApacheCombinedIO)
+	line := `192.0.2.1 - - [10/Oct/2000:13:55:36 -0700] "GET /file.iso HTTP/1.1" 200 1048576 "-" "curl/8.0" 120 1048900`
+	log, err := p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.Equal("192.0.2.1", log.Client)
+		as.Equal("/file.iso", log.URL)
+		as.EqualValues(1048900, log.Size)
+		as.Equal("curl/8.0", log.Useragent)
+	}
+
+	// combined format shall not be accepted
+	_, err = p.Parse([]byte(`192.0.2.1 - - [10/Oct/2000:13:55:36 -0700] "GET /file.iso HTTP/1.1" 200 1048576 "-" "curl/8.0"`))
+	as.Error(err)
+}
@@ -28,11 +28,15 @@ func init() {
 
 type CaddyJsonLogHeader struct {
 	Useragent []string `json:"User-Agent"`
+	Referer   []string `json:"Referer"`
 }
 
 type CaddyJsonLogRequest struct {
 	RemoteIP string             `json:"remote_ip"`
 	ClientIP string             `json:"client_ip"`
+	Proto    string             `json:"proto"`
+	Method   string             `json:"method"`
+	Host     string             `json:"host"`
 	Uri      string             `json:"uri"`
 	Headers  CaddyJsonLogHeader `json:"headers"`
 }
@@ -41,7 +45,9 @@ type CaddyJsonLog struct {
 	Msg       string              `json:"msg"`
 	Timestamp float64             `json:"ts"` // (unix_seconds_float)
 	Request   CaddyJsonLogRequest `json:"request"`
+	Duration  float64             `json:"duration"` // (seconds_float)
 	Size      uint64              `json:"size"`
+	Status    int                 `json:"status"`
 }
 
 func ParseCaddyJSON(line []byte) (LogItem, error) {
@@ -65,5 +71,12 @@ func ParseCaddyJSON(line []byte) (LogItem, error) {
 		Time:      t,
 		URL:       logItem.Request.Uri,
 		Useragent: strings.Join(logItem.Request.Headers.Useragent, ", "),
+
+		Status:      logItem.Status,
+		Method:      logItem.Request.Method,
+		Referer:     strings.Join(logItem.Request.Headers.Referer, ", "),
+		Host:        logItem.Request.Host,
+		Protocol:    logItem.Request.Proto,
+		RequestTime: secondsToDuration(logItem.Duration),
 	}, nil
 }
@@ -19,4 +19,9 @@ func TestCaddyJSONParser(t *testing.T) {
 	expectedTime := time.Unix(1646861401, 524102400)
 	as.WithinDuration(expectedTime, log.Time, time.Microsecond)
 	as.Equal("curl/7.82.0", log.Useragent)
+	as.Equal(200, log.Status)
+	as.Equal("GET", log.Method)
+	as.Equal("localhost", log.Host)
+	as.Equal("HTTP/2.0", log.Protocol)
+	as.Equal(929675*time.Nanosecond, log.RequestTime)
 }
@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+func init() {
+	RegisterParser(ParserMeta{
+		Name:        "cloudfront",
+		Description: "Amazon CloudFront standard log format (W3C, honors #Fields header)",
+		F:           func() Parser { return NewCloudFrontParser() },
+	})
+}
+
+// Fields in CloudFront standard logs, used before any #Fields header is seen
+var cloudFrontDefaultFields = []string{
+	"date", "time", "x-edge-location", "sc-bytes", "c-ip", "cs-method", "cs(Host)", "cs-uri-stem",
+	"sc-status", "cs(Referer)", "cs(User-Agent)", "cs-uri-query", "cs(Cookie)", "x-edge-result-type",
+	"x-edge-request-id", "x-host-header", "cs-protocol", "cs-bytes", "time-taken", "x-forwarded-for",
+	"ssl-protocol", "ssl-cipher", "x-edge-response-result-type", "cs-protocol-version", "fle-status",
+	"fle-encrypted-fields", "c-port", "time-to-first-byte", "x-edge-detailed-result-type",
+	"sc-content-type", "sc-content-len", "sc-range-start", "sc-range-end",
+}
+
+// CloudFrontParser parses tab-separated W3C logs.
+// It's stateful as column order follows the last #Fields header.
+type CloudFrontParser struct {
+	columns map[string]int
+}
+
+func NewCloudFrontParser() *CloudFrontParser {
+	p := &CloudFrontParser{}
+	p.setFields(cloudFrontDefaultFields)
+	return p
+}
+
+func (p *CloudFrontParser) Stateful() bool {
+	return true
+}
+
+func (p *CloudFrontParser) setFields(fields []string) {
+	p.columns = make(map[string]int, len(fields))
+	for i, f := range fields {
+		p.columns[f] = i
+	}
+}
+
+func (p *CloudFrontParser) get(values [][]byte, name string) string {
+	idx, ok := p.columns[name]
+	if !ok || idx >= len(values) || string(values[idx]) == "-" {
+		return ""
+	}
+	return string(values[idx])
+}
+
+func (p *CloudFrontParser) Parse(line []byte) (logItem LogItem, err error) {
+	if len(line) > 0 && line[0] == '#' {
+		if fields, ok := bytes.CutPrefix(line, []byte("#Fields:")); ok {
+			names := make([]string, 0, len(cloudFrontDefaultFields))
+			for _, f := range bytes.Fields(fields) {
+				names = append(names, string(f))
+			}
+			p.setFields(names)
+		}
+		return LogItem{Discard: true}, nil
+	}
+
+	// Older logs might have fewer fields at the end
+	values := bytes.Split(line, []byte{'\t'})
+	if len(values) < 2 {
+		return logItem, errors.New("invalid format: expected tab-separated fields")
+	}
+
+	logItem.Time, err = time.Parse(time.DateTime, p.get(values, "date")+" "+p.get(values, "time"))
+	if err != nil {
+		return logItem, fmt.Errorf("invalid time: %w", err)
+	}
+	logItem.Client = p.get(values, "c-ip")
+	if size := p.get(values, "sc-bytes"); size != "" {
+		logItem.Size, err = strconv.ParseUint(size, 10, 64)
+		if err != nil {
+			return logItem, fmt.Errorf("invalid size %s: %w", size, err)
+		}
+	}
+	logItem.URL = p.get(values, "cs-uri-stem")
+	// The distribution domain name
+	logItem.Server = p.get(values, "cs(Host)")
+	logItem.Host = p.get(values, "x-host-header")
+	logItem.Method = p.get(values, "cs-method")
+	logItem.Protocol = p.get(values, "cs-protocol-version")
+	logItem.Status, _ = strconv.Atoi(p.get(values, "sc-status"))
+	if secs, err := strconv.ParseFloat(p.get(values, "time-taken"), 64); err == nil {
+		logItem.RequestTime = secondsToDuration(secs)
+	}
+	// These are URL-encoded
+	logItem.Useragent, _ = url.PathUnescape(p.get(values, "cs(User-Agent)"))
+	logItem.Referer, _ = url.PathUnescape(p.get(values, "cs(Referer)"))
+	return
+}
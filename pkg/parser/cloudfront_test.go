@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudFrontParser(t *testing.T) {
+	as := assert.New(t)
+	p, err := GetParser("cloudfront")
+	if !as.NoError(err) {
+		return
+	}
+	line := strings.Join([]string{
+		"2019-12-04", "21:02:31", "LAX1", "392", "192.0.2.100", "GET", "d111111abcdef8.cloudfront.net",
+		"/index.html", "200", "-", "Mozilla/5.0%20(Windows%20NT%2010.0)", "-", "-", "Hit",
+		"SOX4xwn4XV6Q4rgb7XiVGOHms_BGlTAC4KyHmureZmBNrjGdRLiNIQ==", "www.example.com", "https", "43",
+		"0.001", "-", "TLSv1.2", "ECDHE-RSA-AES128-GCM-SHA256", "Hit", "HTTP/2.0", "-", "-", "11040",
+		"0.001", "Hit", "text/html", "78", "-", "-",
+	}, "\t")
+	log, err := p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.Equal("192.0.2.100", log.Client)
+		as.EqualValues(392, log.Size)
+		as.Equal("/index.html", log.URL)
+		as.Equal("d111111abcdef8.cloudfront.net", log.Server)
+		as.Equal("www.example.com", log.Host)
+		as.Equal(200, log.Status)
+		as.Equal("GET", log.Method)
+		as.Equal("HTTP/2.0", log.Protocol)
+		as.Equal("", log.Referer)
+		as.Equal("Mozilla/5.0 (Windows NT 10.0)", log.Useragent)
+		as.Equal(time.Millisecond, log.RequestTime)
+		as.WithinDuration(time.Date(2019, 12, 4, 21, 2, 31, 0, time.UTC), log.Time, 0)
+	}
+
+	// Custom column order from header
+	for _, header := range []string{
+		"#Version: 1.0",
+		"#Fields: c-ip date time cs-uri-stem sc-bytes",
+	} {
+		log, err := p.Parse([]byte(header))
+		if as.NoError(err) {
+			as.True(log.Discard)
+		}
+	}
+	log, err = p.Parse([]byte("2001:db8::1\t2019-12-04\t21:02:31\t/big.iso\t123456"))
+	if as.NoError(err) {
+		as.Equal("2001:db8::1", log.Client)
+		as.Equal("/big.iso", log.URL)
+		as.EqualValues(123456, log.Size)
+		as.Equal("", log.Server)
+	}
+}
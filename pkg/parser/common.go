@@ -3,6 +3,7 @@ package parser
 import (
 	"bytes"
 	"fmt"
+	"math"
 	"time"
 )
 
@@ -17,6 +18,34 @@ func clfDateParseString(s string) time.Time {
 	return t
 }
 
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Round(s * float64(time.Second)))
+}
+
+// splitRequestLine splits "GET /path HTTP/1.1" into its method, URL and protocol parts.
+// Abnormal request lines may lack a method or a protocol, in which case they are left empty.
+func splitRequestLine(requestLine []byte) (method, url, protocol []byte) {
+	url = requestLine
+	// strip HTTP method in url
+	spaceIndex := bytes.IndexByte(url, ' ')
+	if spaceIndex == -1 {
+		// Some abnormal requests do not have a HTTP method
+		// Sliently ignore this case
+	} else {
+		method = url[:spaceIndex]
+		url = url[spaceIndex+1:]
+	}
+	spaceIndex = bytes.IndexByte(url, ' ')
+	if spaceIndex == -1 {
+		// Some abnormal requests do not have a HTTP version
+		// Sliently ignore this case
+	} else {
+		protocol = url[spaceIndex+1:]
+		url = url[:spaceIndex]
+	}
+	return
+}
+
 // Nginx escapes `"`, `\` to `\xXX`
 // Apache esacpes `"`, `\` to `\"` `\\`
 func findEndingDoubleQuote(data []byte) int {
@@ -13,6 +13,24 @@ func TestClfDateParse(t *testing.T) {
 	assert.Equal(t, expected, clfDateParseString(CommonLogFormat))
 }
 
+func TestSplitRequestLine(t *testing.T) {
+	type testCase struct {
+		input                 string
+		method, url, protocol string
+	}
+	testCases := []testCase{
+		{"GET /path HTTP/1.1", "GET", "/path", "HTTP/1.1"},
+		{"GET /path", "GET", "/path", ""},
+		{"/path", "", "/path", ""},
+	}
+	for _, c := range testCases {
+		method, url, protocol := splitRequestLine([]byte(c.input))
+		assert.Equal(t, c.method, string(method))
+		assert.Equal(t, c.url, string(url))
+		assert.Equal(t, c.protocol, string(protocol))
+	}
+}
+
 func TestFindEndingDoubleQuote(t *testing.T) {
 	type testCase struct {
 		input    []byte
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"errors"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// AutoParser is the parser name that asks for detection with Detect.
+const AutoParser = "auto"
+
+type DetectResult struct {
+	Name string
+	// Rate of sample lines parsed successfully
+	Rate float64
+}
+
+var ErrNoParserDetected = errors.New("no parser could parse the sample lines")
+
+// Detect tries all registered (non-hidden) parsers on sample lines,
+// and returns the one with the highest success rate.
+// Parsers not needing options are preferred when rates tie.
+func Detect(lines [][]byte, opts Options) (DetectResult, error) {
+	candidates := All()
+	slices.SortFunc(candidates, func(a, b ParserMeta) int {
+		if (a.FOpts == nil) != (b.FOpts == nil) {
+			if a.FOpts == nil {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	var best DetectResult
+	for _, m := range candidates {
+		if m.Hidden {
+			continue
+		}
+		p, err := GetParserWithOptions(m.Name, opts)
+		if err != nil {
+			// Parsers requiring options are skipped
+			continue
+		}
+		rate := successRate(p, lines)
+		if rate > best.Rate {
+			best = DetectResult{Name: m.Name, Rate: rate}
+		}
+	}
+	if best.Name == "" {
+		return best, ErrNoParserDetected
+	}
+	return best, nil
+}
+
+func successRate(p Parser, lines [][]byte) float64 {
+	total, success := 0, 0
+	for _, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		total++
+		item, err := p.Parse(line)
+		if err != nil || item.Discard {
+			continue
+		}
+		if _, err := netip.ParseAddr(item.Client); err != nil {
+			continue
+		}
+		success++
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(success) / float64(total)
+}
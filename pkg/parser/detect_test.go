@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	as := assert.New(t)
+	testCases := []struct {
+		lines    []string
+		expected string
+	}{
+		{
+			[]string{
+				`123.45.67.8 - - [12/Mar/2023:00:15:32 +0800] "GET /path/to/a/file HTTP/1.1" 200 3009 "-" ""`,
+				`123.45.67.9 - - [12/Mar/2023:00:15:33 +0800] "GET /path/to/b/file HTTP/1.1" 200 4009 "-" "curl/8.0"`,
+			},
+			"nginx-combined",
+		},
+		{
+			[]string{
+				`{"timestamp":1678551332.293,"clientip":"123.45.67.8","serverip":"87.65.4.32","method":"GET","url":"/path/to/a/file","status":200,"size":3009,"resp_time":0.000,"http_host":"example.com","referer":"","user_agent":""}`,
+				`not a json line`,
+			},
+			"nginx-json",
+		},
+		{
+			[]string{
+				`{"level":"info","ts":1646861401.5241024,"logger":"http.log.access","msg":"handled request","request":{"remote_ip":"127.0.0.1","client_ip":"127.0.0.1","uri":"/","headers":{"User-Agent":["curl/7.82.0"]}},"size":10900,"status":200}`,
+			},
+			"caddy-json",
+		},
+	}
+	for _, c := range testCases {
+		lines := make([][]byte, len(c.lines))
+		for i, l := range c.lines {
+			lines[i] = []byte(l)
+		}
+		res, err := Detect(lines, nil)
+		if as.NoError(err) {
+			as.Equal(c.expected, res.Name)
+		}
+	}
+
+	_, err := Detect([][]byte{[]byte("garbage")}, nil)
+	as.ErrorIs(err, ErrNoParserDetected)
+}
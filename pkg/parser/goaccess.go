@@ -1,8 +1,9 @@
 package parser
 
 import (
-	"log"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/taoky/goaccessfmt/pkg/goaccessfmt"
 )
@@ -10,13 +11,17 @@ import (
 func init() {
 	RegisterParser(ParserMeta{
 		Name:        "goaccess",
-		Description: "GoAccess output format",
-		F: func() Parser {
-			parser, err := GoAccessFormatParser{}.new()
+		Description: "GoAccess output format, set config file with GOACCESS_CONFIG env or `--parser-opt config=...`",
+		FOpts: func(opts Options) (Parser, error) {
+			confFile := opts["config"]
+			if confFile == "" {
+				confFile = os.Getenv("GOACCESS_CONFIG")
+			}
+			parser, err := GoAccessFormatParser{}.new(confFile)
 			if err != nil {
-				log.Fatalln("goaccess init failed (You might need to set GOACCESS_CONFIG env)\n", err)
+				return nil, fmt.Errorf("goaccess init failed (You might need to set GOACCESS_CONFIG env): %w", err)
 			}
-			return parser
+			return parser, nil
 		},
 	})
 }
@@ -25,8 +30,7 @@ type GoAccessFormatParser struct {
 	conf goaccessfmt.Config
 }
 
-func (p GoAccessFormatParser) new() (GoAccessFormatParser, error) {
-	confFile := os.Getenv("GOACCESS_CONFIG")
+func (p GoAccessFormatParser) new(confFile string) (GoAccessFormatParser, error) {
 	file, err := os.Open(confFile)
 	if err != nil {
 		return p, err
@@ -52,5 +56,13 @@ func (p GoAccessFormatParser) Parse(line []byte) (LogItem, error) {
 		URL:       glogitem.Req,
 		Server:    glogitem.Server,
 		Useragent: glogitem.Agent,
+
+		Status:   glogitem.Status,
+		Method:   glogitem.Method,
+		Referer:  glogitem.Ref,
+		Host:     glogitem.VHost,
+		Protocol: glogitem.Protocol,
+		// goaccess keeps serve time in microseconds
+		RequestTime: time.Duration(glogitem.ServeTime) * time.Microsecond,
 	}, nil
 }
@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterParser(ParserMeta{
+		Name:        "haproxy",
+		Description: "HAProxy's default `httplog` format, with or without syslog prefix",
+		F:           func() Parser { return ParserFunc(ParseHAProxy) },
+	})
+}
+
+const haproxyAcceptDate = "02/Jan/2006:15:04:05.000"
+
+// splitHostPort splits "1.2.3.4:5678", "[2001:db8::1]:5678" or "2001:db8::1:5678" into address and port.
+func splitHostPort(s []byte) (netip.Addr, []byte, error) {
+	colonIdx := bytes.LastIndexByte(s, ':')
+	if colonIdx == -1 {
+		return netip.Addr{}, nil, errors.New("no port")
+	}
+	host := s[:colonIdx]
+	if len(host) > 2 && host[0] == '[' && host[len(host)-1] == ']' {
+		host = host[1 : len(host)-1]
+	}
+	addr, err := netip.ParseAddr(string(host))
+	return addr, s[colonIdx+1:], err
+}
+
+func isHAProxyClient(token []byte) bool {
+	if spaceIdx := bytes.IndexByte(token, ' '); spaceIdx != -1 {
+		token = token[:spaceIdx]
+	}
+	_, _, err := splitHostPort(token)
+	return err == nil
+}
+
+// stripSyslogPrefix removes things like "Feb  6 12:14:14 localhost haproxy[14389]: ",
+// by looking for the first ": " followed by client address.
+func stripSyslogPrefix(line []byte) ([]byte, error) {
+	if isHAProxyClient(line) {
+		return line, nil
+	}
+	rest := line
+	for {
+		idx := bytes.Index(rest, []byte(": "))
+		if idx == -1 {
+			return nil, errors.New("unexpected format: client address not found")
+		}
+		rest = rest[idx+2:]
+		if isHAProxyClient(rest) {
+			return rest, nil
+		}
+	}
+}
+
+func ParseHAProxy(line []byte) (logItem LogItem, err error) {
+	line, err = stripSyslogPrefix(line)
+	if err != nil {
+		return logItem, err
+	}
+	fields, err := splitFields(line)
+	if err != nil {
+		return logItem, err
+	}
+	// Captured headers might take more fields
+	if len(fields) < 13 {
+		return logItem, fmt.Errorf("invalid format: expected at least 13 fields, got %d", len(fields))
+	}
+
+	client, _, err := splitHostPort(fields[0])
+	if err != nil {
+		return logItem, fmt.Errorf("invalid client %s: %w", fields[0], err)
+	}
+	logItem.Client = client.String()
+
+	logItem.Time, err = time.ParseInLocation(haproxyAcceptDate, string(fields[1]), time.Local)
+	if err != nil {
+		return logItem, fmt.Errorf("invalid accept date: %w", err)
+	}
+
+	// "~" suffix means SSL frontend
+	logItem.Host = strings.TrimSuffix(string(fields[2]), "~")
+	backend, _, _ := bytes.Cut(fields[3], []byte{'/'})
+	logItem.Server = string(backend)
+
+	// TR/Tw/Tc/Tr/Ta, Ta is -1 for aborted requests
+	timers := bytes.Split(fields[4], []byte{'/'})
+	if ta, err := strconv.Atoi(strings.TrimPrefix(string(timers[len(timers)-1]), "+")); err == nil && ta >= 0 {
+		logItem.RequestTime = time.Duration(ta) * time.Millisecond
+	}
+
+	logItem.Status, err = strconv.Atoi(string(fields[5]))
+	if err != nil {
+		return logItem, fmt.Errorf("invalid status %s: %w", fields[5], err)
+	}
+	// With "option logasap" bytes_read is prefixed by "+"
+	logItem.Size, err = strconv.ParseUint(strings.TrimPrefix(string(fields[6]), "+"), 10, 64)
+	if err != nil {
+		return logItem, fmt.Errorf("invalid size %s: %w", fields[6], err)
+	}
+
+	method, url, protocol := splitRequestLine(fields[len(fields)-1])
+	logItem.Method = string(method)
+	logItem.URL = string(url)
+	logItem.Protocol = string(protocol)
+	return
+}
@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHAProxyParser(t *testing.T) {
+	as := assert.New(t)
+	p, err := GetParser("haproxy")
+	if !as.NoError(err) {
+		return
+	}
+	expectedTime := time.Date(2009, 2, 6, 12, 14, 14, 655000000, time.Local)
+	for _, line := range []string{
+		`Feb  6 12:14:14 localhost haproxy[14389]: 10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 {1wt.eu} {} "GET /index.html HTTP/1.1"`,
+		`10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 {1wt.eu} {} "GET /index.html HTTP/1.1"`,
+		`<134>Feb  6 12:14:14 haproxy[14389]: 10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 "GET /index.html HTTP/1.1"`,
+	} {
+		log, err := p.Parse([]byte(line))
+		if as.NoError(err, line) {
+			as.Equal("10.0.1.2", log.Client)
+			as.WithinDuration(expectedTime, log.Time, 0)
+			as.Equal("http-in", log.Host)
+			as.Equal("static", log.Server)
+			as.Equal(109*time.Millisecond, log.RequestTime)
+			as.Equal(200, log.Status)
+			as.EqualValues(2750, log.Size)
+			as.Equal("GET", log.Method)
+			as.Equal("/index.html", log.URL)
+			as.Equal("HTTP/1.1", log.Protocol)
+		}
+	}
+
+	line := `haproxy[14389]: 2001:db8::1:33317 [06/Feb/2009:12:14:14.655] https-in~ mirrors/<NOSRV> -1/-1/-1/-1/+5 400 +187 - - PR-- 0/0/0/0/0 0/0 "<BADREQ>"`
+	log, err := p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.Equal("2001:db8::1", log.Client)
+		as.Equal("https-in", log.Host)
+		as.Equal("mirrors", log.Server)
+		as.Equal(5*time.Millisecond, log.RequestTime)
+		as.EqualValues(187, log.Size)
+		as.Equal("<BADREQ>", log.URL)
+	}
+}
@@ -0,0 +1,230 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+func init() {
+	RegisterParser(ParserMeta{
+		Name:        "json",
+		Description: "JSON with configurable fields, set with `--parser-opt client=... --parser-opt size=...`, see README.md for details",
+		FOpts:       newJSONMappingParser,
+	})
+}
+
+type jsonSetter func(item *LogItem, value string, p *JSONMappingParser) error
+
+var jsonFields = map[string]jsonSetter{
+	"client": func(item *LogItem, value string, _ *JSONMappingParser) error {
+		item.Client = value
+		return nil
+	},
+	"size": func(item *LogItem, value string, _ *JSONMappingParser) (err error) {
+		item.Size, err = strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			// Some loggers write integers in float form
+			var f float64
+			f, err = strconv.ParseFloat(value, 64)
+			item.Size = uint64(f)
+		}
+		return
+	},
+	"time": func(item *LogItem, value string, p *JSONMappingParser) (err error) {
+		item.Time, err = p.parseTime(value)
+		return
+	},
+	"url": func(item *LogItem, value string, _ *JSONMappingParser) error {
+		item.URL = value
+		return nil
+	},
+	"server": func(item *LogItem, value string, _ *JSONMappingParser) error {
+		item.Server = value
+		return nil
+	},
+	"useragent": func(item *LogItem, value string, _ *JSONMappingParser) error {
+		item.Useragent = value
+		return nil
+	},
+	"status": func(item *LogItem, value string, _ *JSONMappingParser) (err error) {
+		item.Status, err = strconv.Atoi(value)
+		return
+	},
+	"method": func(item *LogItem, value string, _ *JSONMappingParser) error {
+		item.Method = value
+		return nil
+	},
+	"referer": func(item *LogItem, value string, _ *JSONMappingParser) error {
+		item.Referer = value
+		return nil
+	},
+	"host": func(item *LogItem, value string, _ *JSONMappingParser) error {
+		item.Host = value
+		return nil
+	},
+	"protocol": func(item *LogItem, value string, _ *JSONMappingParser) error {
+		item.Protocol = value
+		return nil
+	},
+	"request_time": func(item *LogItem, value string, _ *JSONMappingParser) error {
+		secs, err := strconv.ParseFloat(value, 64)
+		item.RequestTime = secondsToDuration(secs)
+		return err
+	},
+}
+
+const (
+	JSONTimeUnix    = "unix"
+	JSONTimeUnixMs  = "unix_ms"
+	JSONTimeRFC3339 = "rfc3339"
+	JSONTimeCLF     = "clf"
+)
+
+type jsonField struct {
+	name string
+	path string
+	set  jsonSetter
+}
+
+// JSONMappingParser parses JSON logs with user-given field paths.
+// Paths are dotted, like "request.client_ip".
+type JSONMappingParser struct {
+	fields     []jsonField
+	timeFormat string
+}
+
+func newJSONMappingParser(opts Options) (Parser, error) {
+	p := &JSONMappingParser{timeFormat: JSONTimeUnix}
+	for key, value := range opts {
+		if key == "time_format" {
+			switch value {
+			case JSONTimeUnix, JSONTimeUnixMs, JSONTimeRFC3339, JSONTimeCLF:
+				p.timeFormat = value
+			default:
+				return nil, fmt.Errorf("unknown time_format %q", value)
+			}
+			continue
+		}
+		set, ok := jsonFields[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown json field %q", key)
+		}
+		p.fields = append(p.fields, jsonField{name: key, path: value, set: set})
+	}
+	if _, ok := opts["client"]; !ok {
+		return nil, errors.New("json parser requires at least client option")
+	}
+	slices.SortFunc(p.fields, func(a, b jsonField) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return p, nil
+}
+
+func (p *JSONMappingParser) parseTime(value string) (time.Time, error) {
+	switch p.timeFormat {
+	case JSONTimeUnix:
+		secs, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		sec, dec := math.Modf(secs)
+		return time.Unix(int64(sec), int64(dec*1e9)), nil
+	case JSONTimeUnixMs:
+		if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return time.UnixMilli(ms), nil
+		}
+		ms, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, int64(ms*1e6)), nil
+	case JSONTimeRFC3339:
+		return time.Parse(time.RFC3339Nano, value)
+	case JSONTimeCLF:
+		return time.Parse(CommonLogFormat, value)
+	}
+	return time.Time{}, fmt.Errorf("unknown time format %q", p.timeFormat)
+}
+
+// lookupJSONPath finds the value of a dotted path in a decoded object.
+// A key containing dots itself (like "http.request.method") is matched before descending.
+func lookupJSONPath(obj map[string]any, path string) (any, bool) {
+	if value, ok := obj[path]; ok {
+		return value, true
+	}
+	for i := strings.IndexByte(path, '.'); i != -1; {
+		if child, ok := obj[path[:i]].(map[string]any); ok {
+			if found, ok := lookupJSONPath(child, path[i+1:]); ok {
+				return found, true
+			}
+		}
+		next := strings.IndexByte(path[i+1:], '.')
+		if next == -1 {
+			break
+		}
+		i += next + 1
+	}
+	return nil, false
+}
+
+// jsonValueString converts a decoded JSON scalar to its string form.
+// Arrays of strings (like Caddy's headers) are joined with ", ".
+func jsonValueString(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return string(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case []any:
+		ss := make([]string, 0, len(v))
+		for _, elem := range v {
+			s, ok := elem.(string)
+			if !ok {
+				return "", errors.New("expected an array of strings")
+			}
+			ss = append(ss, s)
+		}
+		return strings.Join(ss, ", "), nil
+	}
+	return "", fmt.Errorf("unexpected JSON value of type %T", value)
+}
+
+func (p *JSONMappingParser) Parse(line []byte) (LogItem, error) {
+	// Decode once, keeping numbers in their original form
+	var obj map[string]any
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&obj); err != nil {
+		return LogItem{}, err
+	}
+	var item LogItem
+	for _, f := range p.fields {
+		v, ok := lookupJSONPath(obj, f.path)
+		if !ok {
+			continue
+		}
+		value, err := jsonValueString(v)
+		if err != nil {
+			return item, fmt.Errorf("invalid %s: %w", f.name, err)
+		}
+		if value == "" {
+			continue
+		}
+		if err := f.set(&item, value, p); err != nil {
+			return item, fmt.Errorf("invalid %s %q: %w", f.name, value, err)
+		}
+	}
+	return item, nil
+}
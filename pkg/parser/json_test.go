@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONMappingParser(t *testing.T) {
+	as := assert.New(t)
+	p, err := GetParserWithOptions("json", Options{
+		"client":       "request.client_ip",
+		"size":         "size",
+		"time":         "ts",
+		"url":          "request.uri",
+		"useragent":    "request.headers.User-Agent",
+		"status":       "status",
+		"request_time": "duration",
+	})
+	if !as.NoError(err) {
+		return
+	}
+	line := `{"level":"info","ts":1646861401.5241024,"logger":"http.log.access","msg":"handled request","request":{"remote_ip":"127.0.0.1","remote_port":"41342","client_ip":"127.0.0.1","proto":"HTTP/2.0","method":"GET","host":"localhost","uri":"/","headers":{"User-Agent":["curl/7.82.0"],"Accept":["*/*"],"Accept-Encoding":["gzip, deflate, br"]},"tls":{"resumed":false,"version":772,"cipher_suite":4865,"proto":"h2","server_name":"example.com"}},"bytes_read": 0,"user_id":"","duration":0.000929675,"size":10900,"status":200,"resp_headers":{"Server":["Caddy"],"Content-Encoding":["gzip"],"Content-Type":["text/html; charset=utf-8"],"Vary":["Accept-Encoding"]}}`
+	log, err := p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.Equal("/", log.URL)
+		as.EqualValues(10900, log.Size)
+		as.Equal("127.0.0.1", log.Client)
+		as.WithinDuration(time.Unix(1646861401, 524102400), log.Time, time.Microsecond)
+		as.Equal("curl/7.82.0", log.Useragent)
+		as.Equal(200, log.Status)
+		as.Equal(929675*time.Nanosecond, log.RequestTime)
+	}
+}
+
+func TestJSONMappingParserTimeFormats(t *testing.T) {
+	as := assert.New(t)
+	expected := time.Date(2024, 9, 30, 18, 1, 35, 0, time.UTC)
+	testCases := []struct {
+		format string
+		line   string
+	}{
+		{JSONTimeUnixMs, `{"ip":"1.2.3.4","t":1727719295000}`},
+		{JSONTimeUnixMs, `{"ip":"1.2.3.4","t":1727719295000.5}`},
+		{JSONTimeRFC3339, `{"ip":"1.2.3.4","t":"2024-09-30T18:01:35Z"}`},
+		{JSONTimeCLF, `{"ip":"1.2.3.4","t":"30/Sep/2024:18:01:35 +0000"}`},
+	}
+	for _, c := range testCases {
+		p, err := GetParserWithOptions("json", Options{"client": "ip", "time": "t", "time_format": c.format})
+		if !as.NoError(err) {
+			continue
+		}
+		log, err := p.Parse([]byte(c.line))
+		if as.NoError(err, c.format) {
+			as.WithinDuration(expected, log.Time, time.Millisecond, c.format)
+		}
+	}
+}
+
+func TestJSONMappingParserDottedKeys(t *testing.T) {
+	as := assert.New(t)
+	p, err := GetParserWithOptions("json", Options{
+		"client": "source.ip",
+		"size":   "http.response.body.bytes",
+		"method": "http.request.method",
+	})
+	if !as.NoError(err) {
+		return
+	}
+	line := `{"source.ip":"2001:db8::1","http.response.body.bytes":"42","http":{"request":{"method":"HEAD"}}}`
+	log, err := p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.Equal("2001:db8::1", log.Client)
+		as.EqualValues(42, log.Size)
+		as.Equal("HEAD", log.Method)
+	}
+}
+
+func TestJSONMappingParserInvalidOptions(t *testing.T) {
+	_, err := GetParserWithOptions("json", nil)
+	assert.Error(t, err)
+	_, err = GetParserWithOptions("json", Options{"client": "ip", "foo": "bar"})
+	assert.Error(t, err)
+	_, err = GetParserWithOptions("json", Options{"client": "ip", "time_format": "iso"})
+	assert.Error(t, err)
+}
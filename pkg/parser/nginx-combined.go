@@ -1,11 +1,11 @@
 package parser
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 func init() {
@@ -52,24 +52,13 @@ func ParseNginxCombined(line []byte) (logItem LogItem, err error) {
 	logItem.Client = string(fields[0])
 	logItem.Time = clfDateParse(fields[3])
 
-	requestLine := fields[4]
-	url := requestLine
-	// strip HTTP method in url
-	spaceIndex := bytes.IndexByte(url, ' ')
-	if spaceIndex == -1 {
-		// Some abnormal requests do not have a HTTP method
-		// Sliently ignore this case
-	} else {
-		url = url[spaceIndex+1:]
-	}
-	spaceIndex = bytes.IndexByte(url, ' ')
-	if spaceIndex == -1 {
-		// Some abnormal requests do not have a HTTP version
-		// Sliently ignore this case
-	} else {
-		url = url[:spaceIndex]
-	}
+	method, url, protocol := splitRequestLine(fields[4])
+	logItem.Method = string(method)
 	logItem.URL = string(url)
+	logItem.Protocol = string(protocol)
+
+	// Optional, left zero if invalid
+	logItem.Status, _ = strconv.Atoi(string(fields[5]))
 
 	sizeBytes := fields[6]
 	logItem.Size, err = strconv.ParseUint(string(sizeBytes), 10, 64)
@@ -77,6 +66,7 @@ func ParseNginxCombined(line []byte) (logItem LogItem, err error) {
 		return logItem, err
 	}
 
+	logItem.Referer = string(fields[7])
 	logItem.Useragent = string(fields[8])
 	return
 }
@@ -94,11 +84,17 @@ func ParseNginxCombinedRegex(line []byte) (LogItem, error) {
 	if err != nil {
 		return LogItem{}, fmt.Errorf("invalid size %s: %w", m[8], err)
 	}
+	// Optional, left zero if invalid
+	status, _ := strconv.Atoi(m[7])
 	return LogItem{
 		Client:    m[1],
 		Time:      clfDateParseString(m[3]),
 		URL:       m[5],
 		Size:      size,
 		Useragent: m[10],
+		Status:    status,
+		Method:    strings.TrimSuffix(m[4], " "),
+		Protocol:  strings.TrimPrefix(m[6], " "),
+		Referer:   m[9],
 	}, nil
 }
@@ -18,6 +18,20 @@ func testNginxCombinedParser(t *testing.T, p Parser) {
 		expectedTime := time.Date(2023, 3, 12, 0, 15, 32, 0, time.FixedZone("CST", 8*60*60))
 		as.WithinDuration(expectedTime, log.Time, 0)
 		as.Equal("", log.Useragent)
+		as.Equal(200, log.Status)
+		as.Equal("GET", log.Method)
+		as.Equal("HTTP/1.1", log.Protocol)
+		as.Equal("-", log.Referer)
+	}
+}
+
+func TestNginxCombinedInvalidStatus(t *testing.T) {
+	as := assert.New(t)
+	line := `123.45.67.8 - - [12/Mar/2023:00:15:32 +0800] "GET /path/to/a/file HTTP/1.1" - 3009 "-" ""`
+	log, err := ParseNginxCombined([]byte(line))
+	if as.NoError(err) {
+		as.EqualValues(3009, log.Size)
+		as.Zero(log.Status)
 	}
 }
 
@@ -33,6 +47,9 @@ func testNginxCombinedParserWithUnusualInputs(t *testing.T, p Parser) {
 	if as.NoError(err) {
 		as.Equal(`\x16\x03\x01\x00\xCA\x01\x00\x00\xC6\x03\x03\x94b\x22\x06u\xBEi\xF6\xC5cA\x97eq\xF0\xD5\xD3\xE6\x08I`, log.URL)
 		as.Equal(uint64(163), log.Size)
+		as.Equal(400, log.Status)
+		as.Equal("", log.Method)
+		as.Equal("", log.Protocol)
 	}
 
 	line = `114.5.1.5 - - [04/Apr/2024:09:02:13 +0800] "\x16\x03\x01\x00\xEE\x01\x00\x00\xEA\x03\x03\x9C\xB4\x92\xC5{\xE9\xEC\x18\xB1\x17\x04f\xCA\x0F\xF3\xFD\xAA\x98H\xA5N\xBC\xC9\xD7\xF8\x95.H\x15\x13\xF2\xF9 ~W\xB9\x94Qs\x01\x02\xE3c'\xA8pB\xC5\xCC\x10c\xC9\xF4\x99{\x0E1\x90\x81\xBD4J\x10y\x17\x00&\xC0+\xC0/\xC0,\xC00\xCC\xA9\xCC\xA8\xC0\x09\xC0\x13\xC0" 400 163 "-" "-"`
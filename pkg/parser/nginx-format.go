@@ -0,0 +1,398 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nginx's predefined log format
+const nginxCombinedFormat = `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`
+
+func init() {
+	RegisterParser(ParserMeta{
+		Name:        "nginx-format",
+		Description: "nginx `log_format` string, set with `--parser-opt format=...` or `--parser-opt conf=/etc/nginx/nginx.conf --parser-opt name=...`",
+		FOpts:       newNginxFormatParser,
+	})
+}
+
+func newNginxFormatParser(opts Options) (Parser, error) {
+	format := opts["format"]
+	if format == "" {
+		var err error
+		format, err = nginxFormatFromConf(opts["conf"], opts["name"])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return CompileNginxFormat(format)
+}
+
+func nginxFormatFromConf(conf, name string) (string, error) {
+	if conf == "" {
+		if name != "" && name != "combined" {
+			return "", fmt.Errorf("log_format %q requires conf option", name)
+		}
+		return nginxCombinedFormat, nil
+	}
+	formats, err := ReadNginxLogFormats(conf)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		switch len(formats) {
+		case 0:
+			return nginxCombinedFormat, nil
+		case 1:
+			for _, f := range formats {
+				return f, nil
+			}
+		}
+		return "", fmt.Errorf("multiple log_format found in %s, please set name option", conf)
+	}
+	if f, ok := formats[name]; ok {
+		return f, nil
+	}
+	if name == "combined" {
+		return nginxCombinedFormat, nil
+	}
+	return "", fmt.Errorf("log_format %q not found in %s", name, conf)
+}
+
+type nginxSetter func(item *LogItem, value []byte) error
+
+type nginxSegment struct {
+	// literal text before the variable
+	prefix []byte
+	set    nginxSetter
+	// variable is enclosed in double quotes, so quotes inside are escaped
+	quoted bool
+}
+
+type NginxFormatParser struct {
+	segments []nginxSegment
+	// literal text after the last variable
+	suffix []byte
+}
+
+func parseNginxUint(value []byte) (uint64, error) {
+	if string(value) == "-" {
+		return 0, nil
+	}
+	return strconv.ParseUint(string(value), 10, 64)
+}
+
+func parseNginxSeconds(value []byte) (float64, error) {
+	if string(value) == "-" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(string(value), 64)
+}
+
+var nginxVariables = map[string]nginxSetter{
+	"remote_addr": func(item *LogItem, value []byte) error {
+		item.Client = string(value)
+		return nil
+	},
+	"body_bytes_sent": func(item *LogItem, value []byte) (err error) {
+		item.Size, err = parseNginxUint(value)
+		return
+	},
+	// Only used when $body_bytes_sent is absent from format, see CompileNginxFormat
+	"bytes_sent": func(item *LogItem, value []byte) (err error) {
+		item.Size, err = parseNginxUint(value)
+		return
+	},
+	"request": func(item *LogItem, value []byte) error {
+		method, url, protocol := splitRequestLine(value)
+		if item.URL == "" {
+			item.URL = string(url)
+		}
+		if item.Method == "" {
+			item.Method = string(method)
+		}
+		if item.Protocol == "" {
+			item.Protocol = string(protocol)
+		}
+		return nil
+	},
+	"request_uri": func(item *LogItem, value []byte) error {
+		item.URL = string(value)
+		return nil
+	},
+	"uri": func(item *LogItem, value []byte) error {
+		if item.URL == "" {
+			item.URL = string(value)
+		}
+		return nil
+	},
+	"request_method": func(item *LogItem, value []byte) error {
+		item.Method = string(value)
+		return nil
+	},
+	"server_protocol": func(item *LogItem, value []byte) error {
+		item.Protocol = string(value)
+		return nil
+	},
+	"http_user_agent": func(item *LogItem, value []byte) error {
+		item.Useragent = string(value)
+		return nil
+	},
+	"http_referer": func(item *LogItem, value []byte) error {
+		item.Referer = string(value)
+		return nil
+	},
+	"server_addr": func(item *LogItem, value []byte) error {
+		item.Server = string(value)
+		return nil
+	},
+	"host": func(item *LogItem, value []byte) error {
+		item.Host = string(value)
+		return nil
+	},
+	"http_host": func(item *LogItem, value []byte) error {
+		if item.Host == "" {
+			item.Host = string(value)
+		}
+		return nil
+	},
+	"status": func(item *LogItem, value []byte) (err error) {
+		item.Status, err = strconv.Atoi(string(value))
+		return
+	},
+	"request_time": func(item *LogItem, value []byte) error {
+		secs, err := parseNginxSeconds(value)
+		item.RequestTime = secondsToDuration(secs)
+		return err
+	},
+	"msec": func(item *LogItem, value []byte) error {
+		secs, err := strconv.ParseFloat(string(value), 64)
+		if err != nil {
+			return err
+		}
+		sec, dec := math.Modf(secs)
+		item.Time = time.Unix(int64(sec), int64(dec*1e9))
+		return nil
+	},
+	"time_local": func(item *LogItem, value []byte) error {
+		item.Time = clfDateParse(value)
+		return nil
+	},
+	"time_iso8601": func(item *LogItem, value []byte) (err error) {
+		item.Time, err = time.Parse(time.RFC3339, string(value))
+		return
+	},
+}
+
+func isNginxVariableChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// CompileNginxFormat compiles an nginx log_format string into a parser.
+func CompileNginxFormat(format string) (*NginxFormatParser, error) {
+	p := &NginxFormatParser{}
+	var literal []byte
+	var names []string
+	for i := 0; i < len(format); {
+		if format[i] != '$' {
+			literal = append(literal, format[i])
+			i++
+			continue
+		}
+		var name string
+		if i+1 < len(format) && format[i+1] == '{' {
+			end := strings.IndexByte(format[i+2:], '}')
+			if end == -1 {
+				return nil, fmt.Errorf("unmatched { at %d", i)
+			}
+			name = format[i+2 : i+2+end]
+			i += end + 3
+		} else {
+			j := i + 1
+			for j < len(format) && isNginxVariableChar(format[j]) {
+				j++
+			}
+			name = format[i+1 : j]
+			i = j
+		}
+		if name == "" {
+			return nil, fmt.Errorf("empty variable name at %d", i)
+		}
+		if len(literal) == 0 && len(p.segments) > 0 {
+			return nil, fmt.Errorf("variable $%s directly follows another variable", name)
+		}
+		names = append(names, name)
+		p.segments = append(p.segments, nginxSegment{
+			prefix: literal,
+			// unknown variables have no setter and are skipped
+			set:    nginxVariables[name],
+			quoted: len(literal) > 0 && literal[len(literal)-1] == '"',
+		})
+		literal = nil
+	}
+	if len(p.segments) == 0 {
+		return nil, errors.New("no variables in log format")
+	}
+	p.suffix = literal
+	// $body_bytes_sent is preferred when both present, even if it is 0
+	if slices.Contains(names, "body_bytes_sent") {
+		for i, name := range names {
+			if name == "bytes_sent" {
+				p.segments[i].set = nil
+			}
+		}
+	}
+	return p, nil
+}
+
+func (p *NginxFormatParser) Parse(line []byte) (item LogItem, err error) {
+	pos := 0
+	for i, seg := range p.segments {
+		if !bytes.HasPrefix(line[pos:], seg.prefix) {
+			return item, fmt.Errorf("unexpected format: expected %q at %d", seg.prefix, pos)
+		}
+		pos += len(seg.prefix)
+
+		var next []byte
+		if i+1 < len(p.segments) {
+			next = p.segments[i+1].prefix
+		} else {
+			next = p.suffix
+		}
+		var end int
+		if seg.quoted && len(next) > 0 && next[0] == '"' {
+			end = findEndingDoubleQuote(line[pos:])
+		} else if len(next) == 0 {
+			end = len(line) - pos
+		} else {
+			end = bytes.Index(line[pos:], next)
+		}
+		if end == -1 {
+			return item, fmt.Errorf("unexpected format: expected %q after %d", next, pos)
+		}
+		if seg.set != nil {
+			if err := seg.set(&item, line[pos:pos+end]); err != nil {
+				return item, fmt.Errorf("invalid value %q: %w", line[pos:pos+end], err)
+			}
+		}
+		pos += end
+	}
+	if !bytes.HasPrefix(line[pos:], p.suffix) {
+		return item, fmt.Errorf("unexpected format: expected %q at %d", p.suffix, pos)
+	}
+	pos += len(p.suffix)
+	if rest := bytes.TrimRight(line[pos:], "\r\n"); len(rest) > 0 {
+		return item, fmt.Errorf("unexpected trailing text %q at %d", rest, pos)
+	}
+	return item, nil
+}
+
+// ReadNginxLogFormats reads all log_format definitions in an nginx config file,
+// following include directives.
+func ReadNginxLogFormats(filename string) (map[string]string, error) {
+	formats := make(map[string]string)
+	err := readNginxLogFormats(filename, filepath.Dir(filename), formats)
+	return formats, err
+}
+
+func readNginxLogFormats(filename, confDir string, formats map[string]string) error {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	statements, err := splitNginxStatements(content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	for _, args := range statements {
+		switch args[0] {
+		case "log_format":
+			if len(args) < 3 {
+				return fmt.Errorf("%s: invalid log_format directive", filename)
+			}
+			parts := args[2:]
+			if strings.HasPrefix(parts[0], "escape=") {
+				parts = parts[1:]
+			}
+			formats[args[1]] = strings.Join(parts, "")
+		case "include":
+			if len(args) != 2 {
+				continue
+			}
+			pattern := args[1]
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(confDir, pattern)
+			}
+			matches, err := filepath.Glob(pattern)
+			if err != nil {
+				return err
+			}
+			for _, m := range matches {
+				if err := readNginxLogFormats(m, confDir, formats); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// splitNginxStatements tokenizes nginx config into simple statements,
+// with quotes removed. Block openings and closings are ignored.
+func splitNginxStatements(content []byte) ([][]string, error) {
+	var statements [][]string
+	var current []string
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '#':
+			end := bytes.IndexByte(content[i:], '\n')
+			if end == -1 {
+				i = len(content)
+			} else {
+				i += end
+			}
+		case c == ';':
+			if len(current) > 0 {
+				statements = append(statements, current)
+			}
+			current = nil
+			i++
+		case c == '{' || c == '}':
+			current = nil
+			i++
+		case c == '\'' || c == '"':
+			var token []byte
+			j := i + 1
+			for ; j < len(content) && content[j] != c; j++ {
+				if content[j] == '\\' && j+1 < len(content) {
+					j++
+				}
+				token = append(token, content[j])
+			}
+			if j >= len(content) {
+				return nil, fmt.Errorf("unbalanced quotes at %d", i)
+			}
+			current = append(current, string(token))
+			i = j + 1
+		default:
+			j := i
+			for j < len(content) && !strings.ContainsRune(" \t\r\n;{}", rune(content[j])) {
+				j++
+			}
+			current = append(current, string(content[i:j]))
+			i = j
+		}
+	}
+	return statements, nil
+}
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNginxFormatParser(t *testing.T) {
+	as := assert.New(t)
+	p, err := GetParserWithOptions("nginx-format", nil)
+	if !as.NoError(err) {
+		return
+	}
+	// Default to nginx's combined format
+	testNginxCombinedParser(t, p)
+	testNginxCombinedParserWithUnusualInputs(t, p)
+
+	p, err = GetParserWithOptions("nginx-format", Options{
+		"format": `$msec $remote_addr $server_addr "$request_method ${request_uri}" $status $bytes_sent $request_time "$http_user_agent" $upstream_cache_status`,
+	})
+	if !as.NoError(err) {
+		return
+	}
+	line := `1678551332.293 123.45.67.8 87.65.4.32 "GET /path/to/a/file" 206 3009 0.125 "Wget/1.21" HIT`
+	log, err := p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.EqualValues(3009, log.Size)
+		as.Equal("123.45.67.8", log.Client)
+		as.Equal("87.65.4.32", log.Server)
+		as.Equal("/path/to/a/file", log.URL)
+		as.Equal("GET", log.Method)
+		as.Equal(206, log.Status)
+		as.Equal(125*time.Millisecond, log.RequestTime)
+		as.Equal("Wget/1.21", log.Useragent)
+		as.WithinDuration(time.Unix(1678551332, 293000000), log.Time, time.Microsecond)
+	}
+
+	_, err = p.Parse([]byte(`123.45.67.8 - - [12/Mar/2023:00:15:32 +0800] "GET / HTTP/1.1" 200 3009 "-" ""`))
+	as.Error(err)
+}
+
+func TestNginxFormatBytesSent(t *testing.T) {
+	as := assert.New(t)
+	p, err := CompileNginxFormat(`$remote_addr "$request" $status $body_bytes_sent $bytes_sent "end"`)
+	if !as.NoError(err) {
+		return
+	}
+	// Header-only response
+	log, err := p.Parse([]byte(`123.45.67.8 "HEAD /a HTTP/1.1" 200 0 312 "end"`))
+	if as.NoError(err) {
+		as.EqualValues(0, log.Size)
+	}
+	log, err = p.Parse([]byte(`123.45.67.8 "GET /a HTTP/1.1" 200 1000 1312 "end"`))
+	if as.NoError(err) {
+		as.EqualValues(1000, log.Size)
+	}
+
+	_, err = p.Parse([]byte(`123.45.67.8 "GET /a HTTP/1.1" 200 1000 1312 "end" extra`))
+	as.Error(err)
+	_, err = p.Parse([]byte("123.45.67.8 \"GET /a HTTP/1.1\" 200 1000 1312 \"end\"\r\n"))
+	as.NoError(err)
+}
+
+func TestCompileNginxFormatErrors(t *testing.T) {
+	for _, format := range []string{
+		"no variables",
+		"$remote_addr$status",
+		"${remote_addr",
+	} {
+		_, err := CompileNginxFormat(format)
+		assert.Error(t, err, format)
+	}
+}
+
+func TestReadNginxLogFormats(t *testing.T) {
+	as := assert.New(t)
+	dir := t.TempDir()
+	conf := `
+http {
+    # log_format commented '$remote_addr';
+    log_format main '$remote_addr - $remote_user [$time_local] "$request" '
+                    '$status $body_bytes_sent "$http_referer" '
+                    '"$http_user_agent" "$http_x_forwarded_for"';
+    include conf.d/*.conf;
+}
+`
+	as.NoError(os.WriteFile(filepath.Join(dir, "nginx.conf"), []byte(conf), 0644))
+	as.NoError(os.Mkdir(filepath.Join(dir, "conf.d"), 0755))
+	as.NoError(os.WriteFile(filepath.Join(dir, "conf.d", "json.conf"), []byte(`log_format json escape=json '{"ip":"$remote_addr"}';`), 0644))
+
+	formats, err := ReadNginxLogFormats(filepath.Join(dir, "nginx.conf"))
+	if as.NoError(err) {
+		as.Equal(map[string]string{
+			"main": `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent" "$http_x_forwarded_for"`,
+			"json": `{"ip":"$remote_addr"}`,
+		}, formats)
+	}
+
+	_, err = GetParserWithOptions("nginx-format", Options{"conf": filepath.Join(dir, "nginx.conf")})
+	as.Error(err)
+
+	p, err := GetParserWithOptions("nginx-format", Options{"conf": filepath.Join(dir, "nginx.conf"), "name": "main"})
+	if as.NoError(err) {
+		log, err := p.Parse([]byte(`123.45.67.8 - - [12/Mar/2023:00:15:32 +0800] "GET /file HTTP/1.1" 200 3009 "-" "curl/8.0" "-"`))
+		if as.NoError(err) {
+			as.Equal("/file", log.URL)
+			as.Equal("curl/8.0", log.Useragent)
+		}
+	}
+}
@@ -31,6 +31,11 @@ type NginxJSONLog struct {
 	Timestamp float64 `json:"timestamp"`
 	ServerIP  string  `json:"serverip"`
 	Useragent string  `json:"user_agent"`
+	Method    string  `json:"method"`
+	Status    int     `json:"status"`
+	RespTime  float64 `json:"resp_time"`
+	Host      string  `json:"http_host"`
+	Referer   string  `json:"referer"`
 }
 
 func ParseNginxJSON(line []byte) (LogItem, error) {
@@ -48,5 +53,11 @@ func ParseNginxJSON(line []byte) (LogItem, error) {
 		URL:       logItem.Url,
 		Server:    logItem.ServerIP,
 		Useragent: logItem.Useragent,
+
+		Status:      logItem.Status,
+		Method:      logItem.Method,
+		Referer:     logItem.Referer,
+		Host:        logItem.Host,
+		RequestTime: secondsToDuration(logItem.RespTime),
 	}, nil
 }
@@ -20,4 +20,8 @@ func TestNginxJsonParser(t *testing.T) {
 	expectedTime := time.Unix(1678551332, 293000000)
 	as.WithinDuration(expectedTime, log.Time, time.Microsecond)
 	as.Equal("", log.Useragent)
+	as.Equal(200, log.Status)
+	as.Equal("GET", log.Method)
+	as.Equal("example.com", log.Host)
+	as.Equal(time.Duration(0), log.RequestTime)
 }
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -12,6 +14,14 @@ type LogItem struct {
 	Server    string
 	Useragent string
 
+	// Optional fields, left as zero values when the log format lacks them.
+	Status      int
+	Method      string
+	Referer     string
+	Host        string
+	Protocol    string
+	RequestTime time.Duration
+
 	// Parsers wishing to discard this log item can set Discard to true.
 	Discard bool
 }
@@ -20,6 +30,18 @@ type Parser interface {
 	Parse(line []byte) (LogItem, error)
 }
 
+// StatefulParser is implemented by parsers whose result depends on previous lines
+// (like a header), so they could not start parsing from the middle of a file.
+type StatefulParser interface {
+	Parser
+	Stateful() bool
+}
+
+func IsStateful(p Parser) bool {
+	sp, ok := p.(StatefulParser)
+	return ok && sp.Stateful()
+}
+
 type ParserFunc func(line []byte) (LogItem, error)
 
 func (p ParserFunc) Parse(line []byte) (LogItem, error) {
@@ -28,11 +50,32 @@ func (p ParserFunc) Parse(line []byte) (LogItem, error) {
 
 type NewFunc func() Parser
 
+// NewFuncWithOptions creates a parser that needs user-provided settings.
+type NewFuncWithOptions func(opts Options) (Parser, error)
+
 type ParserMeta struct {
 	Name        string
 	Description string
 	Hidden      bool
 	F           NewFunc
+
+	// FOpts is used instead of F when set.
+	FOpts NewFuncWithOptions
+}
+
+// Options holds parser-specific settings, given as key=value pairs on the command line.
+type Options map[string]string
+
+func ParseOptions(pairs []string) (Options, error) {
+	opts := make(Options, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid parser option %q: expected key=value", pair)
+		}
+		opts[key] = value
+	}
+	return opts, nil
 }
 
 var (
@@ -50,10 +93,17 @@ func (err InvalidParserError) Error() string {
 }
 
 func GetParser(name string) (Parser, error) {
+	return GetParserWithOptions(name, nil)
+}
+
+func GetParserWithOptions(name string, opts Options) (Parser, error) {
 	m, ok := registry[name]
 	if !ok {
 		return nil, InvalidParserError(name)
 	}
+	if m.FOpts != nil {
+		return m.FOpts(opts)
+	}
 	return m.F(), nil
 }
 
@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	newFunc := func() Parser { return ParserFunc(ParseRsyncd) }
+	RegisterParser(ParserMeta{
+		Name:        "rsyncd",
+		Description: "rsyncd's log with `log format = %h %o %f %l %b`",
+		F:           newFunc,
+	})
+}
+
+// ParseRsyncd parses transfer lines like
+// "2024/10/01 00:00:00 [12345] 123.45.67.89 send ubuntu/ls-lR.gz 1234 1240".
+// Other lines (connections, summaries, etc.) are discarded.
+// File names might contain spaces, and runs of them are kept.
+func ParseRsyncd(line []byte) (LogItem, error) {
+	fields := strings.Fields(string(line))
+	if len(fields) < 3 {
+		return LogItem{}, fmt.Errorf("invalid format: expected at least 3 fields, got %d", len(fields))
+	}
+
+	logTime, err := time.ParseInLocation(goLogTime, fields[0]+" "+fields[1], time.Local)
+	if err != nil {
+		return LogItem{}, fmt.Errorf("invalid log time: %w", err)
+	}
+	if !strings.HasPrefix(fields[2], "[") {
+		return LogItem{}, fmt.Errorf("invalid format: expected [pid], got %s", fields[2])
+	}
+	if len(fields) < 8 {
+		return LogItem{Discard: true}, nil
+	}
+	switch fields[4] {
+	case "send", "recv", "del.":
+	default:
+		return LogItem{Discard: true}, nil
+	}
+
+	// %b: bytes actually transferred, rather than %l (file length)
+	size, err := strconv.ParseUint(fields[len(fields)-1], 10, 64)
+	if err != nil {
+		return LogItem{Discard: true}, nil
+	}
+	if _, err := strconv.ParseUint(fields[len(fields)-2], 10, 64); err != nil {
+		return LogItem{Discard: true}, nil
+	}
+	_, rest, ok := cutFields(string(line), 5)
+	var filename string
+	if ok {
+		_, filename, ok = cutLastFields(rest, 2)
+	}
+	if !ok || filename == "" {
+		return LogItem{Discard: true}, nil
+	}
+
+	return LogItem{
+		Client: fields[3],
+		Time:   logTime,
+		URL:    filename,
+		Size:   size,
+		Method: fields[4],
+	}, nil
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRsyncdParser(t *testing.T) {
+	as := assert.New(t)
+	p, err := GetParser("rsyncd")
+	if !as.NoError(err) {
+		return
+	}
+
+	line := `2024/10/01 00:00:00 [12345] 123.45.67.89 send ubuntu/dists/noble/Release 1234 1240`
+	log, err := p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.False(log.Discard)
+		as.Equal("123.45.67.89", log.Client)
+		as.Equal("ubuntu/dists/noble/Release", log.URL)
+		as.EqualValues(1240, log.Size)
+		as.Equal("send", log.Method)
+		expectedTime := time.Date(2024, 10, 1, 0, 0, 0, 0, time.Local)
+		as.WithinDuration(expectedTime, log.Time, 0)
+	}
+
+	line = `2024/10/01 00:00:00 [12345] 2001:db8::1 send debian/a file with spaces.txt 20 20`
+	log, err = p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.Equal("2001:db8::1", log.Client)
+		as.Equal("debian/a file with spaces.txt", log.URL)
+	}
+
+	line = `2024/10/01 00:00:00 [12345] 123.45.67.89 send debian/two  spaces   here.txt 20 20`
+	log, err = p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.Equal("debian/two  spaces   here.txt", log.URL)
+		as.EqualValues(20, log.Size)
+	}
+
+	for _, line := range []string{
+		`2024/10/01 00:00:00 [12345] connect from UNKNOWN (123.45.67.89)`,
+		`2024/10/01 00:00:00 [12345] rsync on ubuntu/ from UNKNOWN (123.45.67.89)`,
+		`2024/10/01 00:00:00 [12345] sent 1841 bytes  received 208 bytes  total size 12345`,
+	} {
+		log, err := p.Parse([]byte(line))
+		if as.NoError(err) {
+			as.True(log.Discard, line)
+		}
+	}
+
+	_, err = p.Parse([]byte(`2024/10/01 00:00:00 server.go:279: client 123.45.67.89 starts requesting module ubuntu`))
+	as.Error(err)
+}
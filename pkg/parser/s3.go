@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+func init() {
+	newFunc := func() Parser { return ParserFunc(ParseS3) }
+	RegisterParser(ParserMeta{
+		Name:        "s3",
+		Description: "Amazon S3 server access log format",
+		F:           newFunc,
+	})
+}
+
+// ParseS3 parses S3 server access logs, using bucket as server.
+func ParseS3(line []byte) (logItem LogItem, err error) {
+	fields, err := splitFields(line)
+	if err != nil {
+		return logItem, err
+	}
+	// Newer fields are appended to the end
+	if len(fields) < 18 {
+		return logItem, fmt.Errorf("invalid format: expected at least 18 fields, got %d", len(fields))
+	}
+
+	logItem.Server = string(fields[1])
+	logItem.Time = clfDateParse(fields[2])
+	logItem.Client = string(fields[3])
+
+	method, url, protocol := splitRequestLine(fields[8])
+	logItem.Method = string(method)
+	logItem.URL = string(url)
+	logItem.Protocol = string(protocol)
+
+	if string(fields[9]) != "-" {
+		logItem.Status, err = strconv.Atoi(string(fields[9]))
+		if err != nil {
+			return logItem, fmt.Errorf("invalid status %s: %w", fields[9], err)
+		}
+	}
+	logItem.Size, err = parseCLFSize(fields[11])
+	if err != nil {
+		return logItem, fmt.Errorf("invalid size %s: %w", fields[11], err)
+	}
+	// Total time in milliseconds
+	if ms, err := strconv.ParseUint(string(fields[13]), 10, 64); err == nil {
+		logItem.RequestTime = time.Duration(ms) * time.Millisecond
+	}
+	logItem.Referer = string(fields[15])
+	logItem.Useragent = string(fields[16])
+	if len(fields) > 22 {
+		logItem.Host = string(fields[22])
+	}
+	return
+}
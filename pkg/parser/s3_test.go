@@ -0,0 +1,37 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestS3Parser(t *testing.T) {
+	as := assert.New(t)
+	p, err := GetParser("s3")
+	if !as.NoError(err) {
+		return
+	}
+	line := `79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be awsexamplebucket1 [06/Feb/2019:00:00:38 +0000] 192.0.2.3 79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be 3E57427F3EXAMPLE REST.GET.OBJECT images/big.iso "GET /awsexamplebucket1/images/big.iso HTTP/1.1" 200 - 113000 113000 7 6 "-" "aws-cli/2.0" - s9lzHYrFp76ZVxRcpX9+5cjAnEH2ROuNkd2BHfIa6UkFVdtjf5mKR3/eTPFvsiP/XV/VLi31234= SigV4 ECDHE-RSA-AES128-GCM-SHA256 AuthHeader awsexamplebucket1.s3.us-west-1.amazonaws.com TLSV1.2 - -`
+	log, err := p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.Equal("awsexamplebucket1", log.Server)
+		as.Equal("192.0.2.3", log.Client)
+		as.Equal("/awsexamplebucket1/images/big.iso", log.URL)
+		as.EqualValues(113000, log.Size)
+		as.Equal(200, log.Status)
+		as.Equal("GET", log.Method)
+		as.Equal(7*time.Millisecond, log.RequestTime)
+		as.Equal("aws-cli/2.0", log.Useragent)
+		as.Equal("awsexamplebucket1.s3.us-west-1.amazonaws.com", log.Host)
+		as.WithinDuration(time.Date(2019, 2, 6, 0, 0, 38, 0, time.UTC), log.Time, 0)
+	}
+
+	line = `79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be awsexamplebucket1 [06/Feb/2019:00:00:38 +0000] 192.0.2.3 79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be 3E57427F3EXAMPLE REST.GET.VERSIONING - "GET /awsexamplebucket1?versioning HTTP/1.1" 200 - - 113 7 - "-" "S3Console/0.4" -`
+	log, err = p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.EqualValues(0, log.Size)
+		as.Equal("", log.Host)
+	}
+}
@@ -44,6 +44,10 @@ func ParseTencentCDN(line []byte) (logItem LogItem, err error) {
 	if err != nil {
 		return logItem, fmt.Errorf("invalid size %s: %w", fields[4], err)
 	}
+	// Optional fields, left zero if invalid
+	status, _ := strconv.Atoi(string(fields[7]))
+	// Request time is in milliseconds
+	reqTime, _ := strconv.ParseUint(string(fields[9]), 10, 64)
 	return LogItem{
 		Size:      size,
 		Client:    string(fields[1]),
@@ -51,5 +55,12 @@ func ParseTencentCDN(line []byte) (logItem LogItem, err error) {
 		URL:       string(fields[3]),
 		Server:    string(fields[2]),
 		Useragent: string(fields[10]),
+
+		Status:      status,
+		Method:      string(fields[12]),
+		Referer:     string(fields[8]),
+		Host:        string(fields[2]),
+		Protocol:    string(fields[13]),
+		RequestTime: time.Duration(reqTime) * time.Millisecond,
 	}, nil
 }
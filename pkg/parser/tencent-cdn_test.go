@@ -24,4 +24,27 @@ func TestTencentCDNParser(t *testing.T) {
 	expectedTime := time.Date(2024, 9, 30, 18, 1, 35, 0, time.Local)
 	as.WithinDuration(expectedTime, log.Time, time.Microsecond)
 	as.Equal("Mozilla/5.0 () Chrome/96.0.4664.104 Mobile Safari/537.36", log.Useragent)
+	as.Equal(200, log.Status)
+	as.Equal("GET", log.Method)
+	as.Equal("https://www.example.com/", log.Referer)
+	as.Equal("www.example.com", log.Host)
+	as.Equal(3*time.Millisecond, log.RequestTime)
+}
+
+func TestTencentCDNParserOptionalFields(t *testing.T) {
+	as := assert.New(t)
+	p, err := GetParser("tencent-cdn")
+	if !(as.NoError(err) && as.NotNil(p)) {
+		return
+	}
+	line := []byte(`20240930180135 123.45.67.8 www.example.com /a.iso 6969 120 2 - - - "curl" "(null)" GET HTTPS hit 32768`)
+	log, err := p.Parse(line)
+	if !as.NoError(err) {
+		return
+	}
+	as.EqualValues(6969, log.Size)
+	as.Equal("123.45.67.8", log.Client)
+	as.Equal("/a.iso", log.URL)
+	as.Zero(log.Status)
+	as.Zero(log.RequestTime)
 }
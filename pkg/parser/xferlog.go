@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	newFunc := func() Parser { return ParserFunc(ParseXferlog) }
+	RegisterParser(ParserMeta{
+		Name:        "xferlog",
+		Description: "wu-ftpd/vsftpd xferlog format",
+		F:           newFunc,
+	})
+	RegisterParser(ParserMeta{
+		Name:        "vsftpd",
+		Description: "An alias for `xferlog`",
+		Hidden:      true,
+		F:           newFunc,
+	})
+}
+
+// Fields after the filename
+const xferlogTrailingFields = 9
+
+var xferlogDirections = map[string]string{
+	"o": "RETR",
+	"i": "STOR",
+	"d": "DELE",
+}
+
+// Completion status mapped to FTP reply codes
+var xferlogStatus = map[string]int{
+	"c": 226,
+	"i": 426,
+}
+
+// cutFields splits n leading space-separated fields of s, and returns them with the rest of s.
+func cutFields(s string, n int) ([]string, string, bool) {
+	fields := make([]string, 0, n)
+	for range n {
+		s = strings.TrimLeft(s, " \t")
+		i := strings.IndexAny(s, " \t")
+		if i == -1 {
+			return nil, "", false
+		}
+		fields = append(fields, s[:i])
+		s = s[i:]
+	}
+	return fields, s[1:], true
+}
+
+// cutLastFields splits n trailing space-separated fields of s, and returns them with the rest of s.
+func cutLastFields(s string, n int) ([]string, string, bool) {
+	fields := make([]string, n)
+	for i := n - 1; i >= 0; i-- {
+		s = strings.TrimRight(s, " \t\r\n")
+		j := strings.LastIndexAny(s, " \t")
+		if j == -1 {
+			return nil, "", false
+		}
+		fields[i] = s[j+1:]
+		s = s[:j]
+	}
+	return fields, s, true
+}
+
+// ParseXferlog parses lines like
+// "Mon Oct  1 00:00:00 2024 1 123.45.67.89 1234 /pub/file.iso b _ o a anonymous@ ftp 0 * c".
+// File names might contain spaces, and runs of them are kept.
+func ParseXferlog(line []byte) (LogItem, error) {
+	fields, rest, ok := cutFields(string(line), 8)
+	var trailing []string
+	var filename string
+	if ok {
+		trailing, filename, ok = cutLastFields(rest, xferlogTrailingFields)
+	}
+	if !ok || filename == "" {
+		return LogItem{}, fmt.Errorf("invalid format: expected at least %d fields", 9+xferlogTrailingFields)
+	}
+
+	logTime, err := time.ParseInLocation(time.ANSIC, strings.Join(fields[:5], " "), time.Local)
+	if err != nil {
+		return LogItem{}, fmt.Errorf("invalid log time: %w", err)
+	}
+	transferTime, err := strconv.ParseUint(fields[5], 10, 64)
+	if err != nil {
+		return LogItem{}, fmt.Errorf("invalid transfer time %s: %w", fields[5], err)
+	}
+	size, err := strconv.ParseUint(fields[7], 10, 64)
+	if err != nil {
+		return LogItem{}, fmt.Errorf("invalid size %s: %w", fields[7], err)
+	}
+
+	return LogItem{
+		Size:        size,
+		Client:      fields[6],
+		Time:        logTime,
+		URL:         filename,
+		Method:      xferlogDirections[trailing[2]],
+		Status:      xferlogStatus[trailing[8]],
+		RequestTime: time.Duration(transferTime) * time.Second,
+	}, nil
+}
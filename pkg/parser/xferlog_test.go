@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXferlogParser(t *testing.T) {
+	as := assert.New(t)
+	p, err := GetParser("xferlog")
+	if !as.NoError(err) {
+		return
+	}
+
+	line := `Tue Oct  1 00:00:00 2024 12 123.45.67.89 104857600 /pub/debian-cd/debian.iso b _ o a anonymous@ ftp 0 * c`
+	log, err := p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.Equal("123.45.67.89", log.Client)
+		as.Equal("/pub/debian-cd/debian.iso", log.URL)
+		as.EqualValues(104857600, log.Size)
+		as.Equal("RETR", log.Method)
+		as.Equal(226, log.Status)
+		as.Equal(12*time.Second, log.RequestTime)
+		expectedTime := time.Date(2024, 10, 1, 0, 0, 0, 0, time.Local)
+		as.WithinDuration(expectedTime, log.Time, 0)
+	}
+
+	line = `Thu Oct 10 12:34:56 2024 0 2001:db8::1 1024 /pub/file with spaces.txt b _ i r user ftp 0 * i`
+	log, err = p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.Equal("2001:db8::1", log.Client)
+		as.Equal("/pub/file with spaces.txt", log.URL)
+		as.Equal("STOR", log.Method)
+		as.Equal(426, log.Status)
+	}
+
+	line = `Thu Oct 10 12:34:56 2024 0 10.0.0.1 1024 /pub/two  spaces   here.txt b _ o a anonymous@ ftp 0 * c`
+	log, err = p.Parse([]byte(line))
+	if as.NoError(err) {
+		as.Equal("/pub/two  spaces   here.txt", log.URL)
+		as.Equal("RETR", log.Method)
+	}
+
+	_, err = p.Parse([]byte(`Thu Oct 10 12:34:56 2024 0 10.0.0.1 1024 b _ o a anonymous@ ftp 0 * c`))
+	as.Error(err)
+}
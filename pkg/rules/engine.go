@@ -0,0 +1,198 @@
+package rules
+
+import (
+	"fmt"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/taoky/ayano/pkg/parser"
+)
+
+// Number of slots in a window
+const windowSlots = 10
+
+// Forget idle counters every sweepInterval items
+const sweepInterval = 4096
+
+// Match is a rule exceeded by a client prefix.
+type Match struct {
+	Rule   *Rule
+	Prefix netip.Prefix
+	// Value reached: bytes, requests, user-agents or repeats
+	Value uint64
+	// Time of the first item counted in the window
+	First time.Time
+	// URL of the last item counted
+	URL string
+}
+
+func (m Match) String() string {
+	return fmt.Sprintf("[%s] %s %s %s %s", m.Rule.Name, m.Prefix, m.Rule.format(m.Value), m.First.Format(time.DateTime), m.URL)
+}
+
+type counterKey struct {
+	prefix netip.Prefix
+	// Only for rules counting each URL separately
+	url string
+}
+
+type slot struct {
+	start time.Time
+	first time.Time
+	value uint64
+}
+
+// counter sums values of a key in a sliding window, in steps of 1/windowSlots of the window.
+type counter struct {
+	slots []slot
+	total uint64
+	// User-agents rules only: last time of each user-agent
+	uas map[string]time.Time
+	// Latest time counted
+	last time.Time
+}
+
+func (c *counter) add(t time.Time, v uint64, within time.Duration) {
+	step := within / windowSlots
+	if t.Before(c.last) {
+		// Lines slightly out of order go to the newest slot
+		t = c.last
+	}
+	c.last = t
+	expired := 0
+	for expired < len(c.slots) && !c.slots[expired].start.Add(within).After(t.Truncate(step)) {
+		c.total -= c.slots[expired].value
+		expired++
+	}
+	c.slots = c.slots[expired:]
+	start := t.Truncate(step)
+	if len(c.slots) == 0 || c.slots[len(c.slots)-1].start.Before(start) {
+		c.slots = append(c.slots, slot{start: start, first: t})
+	}
+	c.slots[len(c.slots)-1].value += v
+	c.total += v
+}
+
+func (c *counter) addUA(t time.Time, ua string, within time.Duration) {
+	if t.Before(c.last) {
+		t = c.last
+	}
+	c.last = t
+	if c.uas == nil {
+		c.uas = make(map[string]time.Time)
+	}
+	c.uas[ua] = t
+	for k, seen := range c.uas {
+		if !seen.Add(within).After(t) {
+			delete(c.uas, k)
+		}
+	}
+	c.total = uint64(len(c.uas))
+}
+
+func (c *counter) first() time.Time {
+	if c.uas != nil {
+		first := c.last
+		for _, seen := range c.uas {
+			if seen.Before(first) {
+				first = seen
+			}
+		}
+		return first
+	}
+	if len(c.slots) == 0 {
+		return c.last
+	}
+	return c.slots[0].first
+}
+
+// Engine counts log items for each rule and client prefix.
+type Engine struct {
+	mu       sync.Mutex
+	rules    []Rule
+	counters []map[counterKey]*counter
+	latest   time.Time
+	observed int
+}
+
+func NewEngine(rules []Rule) *Engine {
+	e := &Engine{}
+	e.Replace(rules)
+	return e
+}
+
+// Replace changes rules, and resets all counters.
+func (e *Engine) Replace(rules []Rule) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = rules
+	e.counters = make([]map[counterKey]*counter, len(rules))
+	for i := range e.counters {
+		e.counters[i] = make(map[counterKey]*counter)
+	}
+}
+
+// Observe counts item from prefix, and returns rules exceeded.
+// Counter of a rule and prefix is reset after it matches, so that it matches
+// again only after exceeding the threshold again.
+func (e *Engine) Observe(prefix netip.Prefix, item parser.LogItem) []Match {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if item.Time.After(e.latest) {
+		e.latest = item.Time
+	}
+	e.observed++
+	if e.observed%sweepInterval == 0 {
+		e.sweep()
+	}
+
+	var matches []Match
+	for i := range e.rules {
+		r := &e.rules[i]
+		if !r.inScope(item) {
+			continue
+		}
+		v := r.value(item)
+		if v == 0 {
+			continue
+		}
+		key := counterKey{prefix: prefix}
+		if r.perURL() {
+			key.url = item.URL
+		}
+		c, ok := e.counters[i][key]
+		if !ok {
+			c = &counter{}
+			e.counters[i][key] = c
+		}
+		if r.UserAgents > 0 {
+			c.addUA(item.Time, item.Useragent, r.Within)
+		} else {
+			c.add(item.Time, v, r.Within)
+		}
+		if c.total > r.threshold() {
+			matches = append(matches, Match{
+				Rule:   r,
+				Prefix: prefix,
+				Value:  c.total,
+				First:  c.first(),
+				URL:    item.URL,
+			})
+			delete(e.counters[i], key)
+		}
+	}
+	return matches
+}
+
+// sweep forgets counters without items in their window, to bound memory.
+func (e *Engine) sweep() {
+	for i, counters := range e.counters {
+		for key, c := range counters {
+			if !c.last.Add(e.rules[i].Within).After(e.latest) {
+				delete(counters, key)
+			}
+		}
+	}
+}
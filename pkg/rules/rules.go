@@ -0,0 +1,165 @@
+// Package rules evaluates alert rules of daemon mode, over sliding windows of log time.
+package rules
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/taoky/ayano/pkg/parser"
+	"github.com/taoky/ayano/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule matches when a client prefix exceeds one threshold within a time window.
+// Only log items in scope (server, dir and ua, if set) are counted.
+type Rule struct {
+	Name string `yaml:"name"`
+
+	// Conditions, exactly one of them must be set
+	Bytes      util.SizeFlag `yaml:"bytes"`
+	Requests   uint64        `yaml:"requests"`
+	UserAgents uint64        `yaml:"user-agents"`
+	// Complete (not partial) downloads of the same URL
+	Repeats uint64 `yaml:"repeats"`
+
+	// Count bytes or requests for each URL separately
+	SameURL bool          `yaml:"same-url"`
+	Within  time.Duration `yaml:"within"`
+
+	// Scope
+	Server string `yaml:"server"`
+	Dir    string `yaml:"dir"`
+	UA     string `yaml:"ua"`
+}
+
+type rulesFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Load reads rules from a YAML file.
+func Load(filename string) ([]Rule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return rules, nil
+}
+
+func Parse(data []byte) ([]Rule, error) {
+	var f rulesFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return nil, err
+	}
+	if len(f.Rules) == 0 {
+		return nil, errors.New("no rules defined")
+	}
+	names := make(map[string]struct{})
+	for i, r := range f.Rules {
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i+1, r.Name, err)
+		}
+		if _, ok := names[r.Name]; ok {
+			return nil, fmt.Errorf("rule %d: duplicate name %q", i+1, r.Name)
+		}
+		names[r.Name] = struct{}{}
+	}
+	return f.Rules, nil
+}
+
+func (r *Rule) validate() error {
+	if r.Name == "" || strings.ContainsAny(r.Name, " \t[]") {
+		return errors.New("name must be non-empty, without spaces or brackets")
+	}
+	conditions := 0
+	for _, set := range []bool{r.Bytes > 0, r.Requests > 0, r.UserAgents > 0, r.Repeats > 0} {
+		if set {
+			conditions++
+		}
+	}
+	if conditions != 1 {
+		return errors.New("exactly one of bytes, requests, user-agents and repeats must be set")
+	}
+	if r.SameURL && r.UserAgents > 0 {
+		return errors.New("same-url does not apply to user-agents")
+	}
+	if r.Within <= 0 {
+		return errors.New("within must be set to a positive duration")
+	}
+	return nil
+}
+
+// inScope reports whether item should be counted by r.
+func (r *Rule) inScope(item parser.LogItem) bool {
+	return (r.Server == "" || item.Server == r.Server) &&
+		(r.Dir == "" || strings.HasPrefix(item.URL, r.Dir)) &&
+		(r.UA == "" || strings.Contains(item.Useragent, r.UA))
+}
+
+func (r *Rule) perURL() bool {
+	return r.SameURL || r.Repeats > 0
+}
+
+func (r *Rule) threshold() uint64 {
+	switch {
+	case r.Bytes > 0:
+		return uint64(r.Bytes)
+	case r.Requests > 0:
+		return r.Requests
+	case r.UserAgents > 0:
+		return r.UserAgents
+	default:
+		return r.Repeats
+	}
+}
+
+// value returns how much item adds to the counter of r.
+func (r *Rule) value(item parser.LogItem) uint64 {
+	switch {
+	case r.Bytes > 0:
+		return item.Size
+	case r.Repeats > 0:
+		// Status is unknown (0) for some log formats
+		if item.Status != 0 && item.Status != 200 {
+			return 0
+		}
+	}
+	return 1
+}
+
+// Condition returns which condition is set: bytes, requests, user-agents or repeats.
+func (r *Rule) Condition() string {
+	switch {
+	case r.Bytes > 0:
+		return "bytes"
+	case r.Requests > 0:
+		return "requests"
+	case r.UserAgents > 0:
+		return "user-agents"
+	default:
+		return "repeats"
+	}
+}
+
+func (r *Rule) format(value uint64) string {
+	switch {
+	case r.Bytes > 0:
+		return humanize.IBytes(value)
+	case r.Requests > 0:
+		return fmt.Sprintf("%d reqs", value)
+	case r.UserAgents > 0:
+		return fmt.Sprintf("%d UAs", value)
+	default:
+		return fmt.Sprintf("%d repeats", value)
+	}
+}
@@ -0,0 +1,125 @@
+package rules
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taoky/ayano/pkg/parser"
+)
+
+func TestParse(t *testing.T) {
+	as := assert.New(t)
+	rules, err := Parse([]byte(`
+rules:
+  - name: bulk
+    bytes: 200GiB
+    within: 1h
+  - name: hammer
+    requests: 5000
+    same-url: true
+    within: 10m
+    dir: /debian/
+`))
+	as.NoError(err)
+	as.Len(rules, 2)
+	as.Equal(uint64(200<<30), uint64(rules[0].Bytes))
+	as.Equal(time.Hour, rules[0].Within)
+	as.True(rules[1].SameURL)
+	as.Equal("/debian/", rules[1].Dir)
+
+	for _, bad := range []string{
+		"rules: []",
+		"rules: [{name: a, within: 1h}]",
+		"rules: [{name: a, bytes: 1G, requests: 1, within: 1h}]",
+		"rules: [{name: a, bytes: 1G}]",
+		"rules: [{name: a b, bytes: 1G, within: 1h}]",
+		"rules: [{name: a, user-agents: 5, same-url: true, within: 1h}]",
+		"rules: [{name: a, bytes: 1G, within: 1h}, {name: a, requests: 1, within: 1h}]",
+		"rules: [{name: a, byte: 1G, within: 1h}]",
+	} {
+		_, err := Parse([]byte(bad))
+		as.Error(err, bad)
+	}
+}
+
+func TestEngine(t *testing.T) {
+	as := assert.New(t)
+	rules, err := Parse([]byte(`
+rules:
+  - name: bulk
+    bytes: 1000
+    within: 10m
+    dir: /big/
+  - name: hammer
+    requests: 3
+    same-url: true
+    within: 1m
+  - name: uas
+    user-agents: 2
+    within: 1m
+  - name: repeats
+    repeats: 2
+    within: 1h
+`))
+	as.NoError(err)
+	e := NewEngine(rules)
+	prefix := netip.MustParsePrefix("10.0.0.0/24")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	observe := func(sec int, url string, size uint64, status int, ua string) []string {
+		var names []string
+		for _, m := range e.Observe(prefix, parser.LogItem{
+			Time: start.Add(time.Duration(sec) * time.Second), URL: url, Size: size, Status: status, Useragent: ua,
+		}) {
+			names = append(names, m.Rule.Name)
+		}
+		return names
+	}
+
+	// Bytes in window, and only in scope
+	as.Empty(observe(0, "/big/a", 600, 206, "a"))
+	as.Empty(observe(1, "/small/a", 600, 206, "a"))
+	// Out of window
+	as.Empty(observe(700, "/big/b", 600, 206, "a"))
+	m := e.Observe(prefix, parser.LogItem{Time: start.Add(710 * time.Second), URL: "/big/c", Size: 600, Status: 206, Useragent: "a"})
+	as.Len(m, 1)
+	as.Equal("[bulk] 10.0.0.0/24 1.2 KiB 2024-01-01 00:11:40 /big/c", m[0].String())
+	// Reset after match
+	as.Empty(observe(720, "/big/d", 600, 206, "a"))
+
+	// Requests for the same URL
+	as.Empty(observe(800, "/x", 1, 206, "a"))
+	as.Empty(observe(801, "/y", 1, 206, "a"))
+	as.Empty(observe(802, "/x", 1, 206, "a"))
+	as.Empty(observe(803, "/x", 1, 206, "a"))
+	as.Equal([]string{"hammer"}, observe(804, "/x", 1, 206, "a"))
+
+	// Distinct user-agents, "a" has expired
+	as.Empty(observe(900, "/z", 1, 206, "b"))
+	as.Empty(observe(901, "/z", 1, 206, "b"))
+	as.Empty(observe(902, "/w", 1, 206, "c"))
+	as.Equal([]string{"uas"}, observe(903, "/w", 1, 206, "d"))
+	as.Empty(observe(950, "/w", 1, 206, "e"))
+
+	// Complete downloads only
+	as.Empty(observe(1000, "/iso", 1, 200, "c"))
+	as.Empty(observe(1100, "/iso", 1, 206, "c"))
+	as.Empty(observe(1200, "/iso", 1, 0, "c"))
+	as.Equal([]string{"repeats"}, observe(1300, "/iso", 1, 200, "c"))
+
+	// Idle counters are forgotten
+	for range sweepInterval {
+		e.Observe(netip.MustParsePrefix("10.0.1.0/24"), parser.LogItem{Time: start.Add(10 * time.Hour), URL: "/", Size: 1, Status: 206, Useragent: "a"})
+	}
+	for i := range e.counters {
+		for key := range e.counters[i] {
+			as.NotEqual(prefix, key.prefix)
+		}
+	}
+}
+
+func TestLoadExample(t *testing.T) {
+	_, err := Load("../../assets/ayano-rules.yaml")
+	assert.NoError(t, err)
+}
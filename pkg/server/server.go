@@ -0,0 +1,132 @@
+// Package server serves state of a running analyzer over HTTP.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/taoky/ayano/pkg/analyze"
+)
+
+// Listen listens on a TCP address (host:port), or a unix socket
+// (unix:/path/to/socket, or an absolute path).
+func Listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, "unix:")
+	if !ok && !strings.HasPrefix(addr, "/") {
+		return net.Listen("tcp", addr)
+	}
+	if !ok {
+		path = addr
+	}
+	// Remove socket left by a previous run
+	if fi, err := os.Lstat(path); err == nil && fi.Mode().Type() == fs.ModeSocket {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return net.Listen("unix", path)
+}
+
+// New returns a handler serving analyzer state.
+func New(a *analyze.Analyzer) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := a.WriteMetrics(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	mux.HandleFunc("GET /api/top", func(w http.ResponseWriter, r *http.Request) {
+		q, ok := parseQuery(w, r, a)
+		if ok {
+			writeJSON(w, map[string]any{"prefixes": a.QueryTop(q)})
+		}
+	})
+	mux.HandleFunc("GET /api/servers", func(w http.ResponseWriter, r *http.Request) {
+		q, ok := parseQuery(w, r, a)
+		if ok {
+			// All servers unless specified
+			q.Server = r.FormValue("server")
+			writeJSON(w, map[string]any{"servers": a.QueryServers(q)})
+		}
+	})
+	mux.HandleFunc("GET /api/dirs", func(w http.ResponseWriter, r *http.Request) {
+		q, ok := parseQuery(w, r, a)
+		if !ok {
+			return
+		}
+		records, ok := a.QueryDirs(q)
+		if !ok {
+			http.Error(w, "directory stats are not kept (start with --track-dirs)", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, map[string]any{"directories": records})
+	})
+	mux.HandleFunc("GET /api/prefix", func(w http.ResponseWriter, r *http.Request) {
+		q, ok := parseQuery(w, r, a)
+		if !ok {
+			return
+		}
+		value := r.FormValue("cidr")
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			// A single address in the prefix
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				http.Error(w, "invalid cidr: "+value, http.StatusBadRequest)
+				return
+			}
+			prefix = a.IPPrefix(addr)
+		}
+		details, ok := a.QueryPrefix(prefix, q)
+		if !ok {
+			http.Error(w, "prefix not found: "+prefix.Masked().String(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, details)
+	})
+	return mux
+}
+
+// parseQuery reads sort, server and limit parameters, or replies with an error.
+func parseQuery(w http.ResponseWriter, r *http.Request, a *analyze.Analyzer) (analyze.Query, bool) {
+	q := a.DefaultQuery()
+	if value := r.FormValue("sort"); value != "" {
+		if err := q.SortBy.Set(value); err != nil {
+			http.Error(w, "invalid sort: "+err.Error(), http.StatusBadRequest)
+			return q, false
+		}
+		if q.SortBy == analyze.SortByRate && !a.Config.TracksRate() {
+			http.Error(w, "invalid sort: rate is not tracked (start with --sort-by rate)", http.StatusBadRequest)
+			return q, false
+		}
+	}
+	if r.Form.Has("server") {
+		q.Server = r.FormValue("server")
+	}
+	if value := r.FormValue("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+			return q, false
+		}
+		q.Limit = n
+	}
+	return q, true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
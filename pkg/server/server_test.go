@@ -0,0 +1,128 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taoky/ayano/pkg/analyze"
+	"github.com/taoky/ayano/pkg/fileiter"
+)
+
+func TestMetrics(t *testing.T) {
+	as := assert.New(t)
+	c := analyze.DefaultConfig()
+	c.NoNetstat = true
+	c.Parser = "nginx-combined"
+	c.Daemon = true
+	c.Listen = "127.0.0.1:0"
+	a, err := analyze.NewAnalyzer(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(New(a))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	as.Equal(http.StatusOK, resp.StatusCode)
+	as.Contains(resp.Header.Get("Content-Type"), "text/plain")
+	as.Contains(string(body), "ayano_lines_total 0\n")
+
+	// Rates are not tracked in daemon mode by default
+	resp, err = http.Get(srv.URL + "/api/top?sort=rate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	as.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestQueryAPI(t *testing.T) {
+	as := assert.New(t)
+	c := analyze.DefaultConfig()
+	c.NoNetstat = true
+	c.Parser = "nginx-combined"
+	c.Filter.Threshold = 0
+	c.Listen = "127.0.0.1:0"
+	c.TrackDirs = true
+	a, err := analyze.NewAnalyzer(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iter := fileiter.NewWithScanner(strings.NewReader(
+		`10.0.0.1 - - [01/Jan/2024:00:00:00 +0000] "GET /debian/a.deb HTTP/1.1" 200 100 "-" "apt"
+10.0.1.1 - - [01/Jan/2024:00:00:01 +0000] "GET /ubuntu/b.iso HTTP/1.1" 200 300 "-" "curl"
+`))
+	as.NoError(a.RunLoop(iter))
+
+	srv := httptest.NewServer(New(a))
+	defer srv.Close()
+	get := func(path string, v any) int {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			as.Equal("application/json", resp.Header.Get("Content-Type"))
+			as.NoError(json.NewDecoder(resp.Body).Decode(v))
+		}
+		return resp.StatusCode
+	}
+
+	var top struct{ Prefixes []analyze.TopRecord }
+	as.Equal(http.StatusOK, get("/api/top?sort=requests&limit=1", &top))
+	as.Len(top.Prefixes, 1)
+	as.Equal(http.StatusOK, get("/api/top?sort=size", &top))
+	as.Len(top.Prefixes, 2)
+	as.Equal("10.0.1.0/24", top.Prefixes[0].CIDR)
+
+	var servers struct{ Servers []analyze.ServerRecord }
+	as.Equal(http.StatusOK, get("/api/servers", &servers))
+	as.Equal([]analyze.ServerRecord{{Server: "", Bytes: 400}}, servers.Servers)
+
+	var dirs struct{ Directories []analyze.DirRecord }
+	as.Equal(http.StatusOK, get("/api/dirs?limit=1", &dirs))
+	as.Len(dirs.Directories, 1)
+	as.Equal("/ubuntu", dirs.Directories[0].Directory)
+
+	var details analyze.PrefixDetails
+	as.Equal(http.StatusOK, get("/api/prefix?cidr=10.0.0.1", &details))
+	as.Equal("10.0.0.0/24", details.CIDR)
+	as.Equal([]string{"apt"}, details.UserAgentList)
+
+	as.Equal(http.StatusNotFound, get("/api/prefix?cidr=10.0.2.0/24", nil))
+	as.Equal(http.StatusBadRequest, get("/api/prefix?cidr=foo", nil))
+	as.Equal(http.StatusBadRequest, get("/api/top?sort=foo", nil))
+	as.Equal(http.StatusBadRequest, get("/api/top?limit=-1", nil))
+}
+
+func TestListenUnix(t *testing.T) {
+	as := assert.New(t)
+	path := filepath.Join(t.TempDir(), "ayano.sock")
+	l, err := Listen("unix:" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	as.Equal("unix", l.Addr().Network())
+	// Unix sockets are not removed if the process exits abnormally
+	l.(interface{ SetUnlinkOnClose(bool) }).SetUnlinkOnClose(false)
+	l.Close()
+
+	// Stale socket is replaced
+	l, err = Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+}
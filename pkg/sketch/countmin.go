@@ -0,0 +1,98 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+const (
+	cmDepth = 4
+	cmWidth = 1 << 14
+)
+
+// CountMin estimates sums of values by key with fixed memory.
+// Estimates never fall below the true sum, and exceed it by at most
+// ErrorBound with probability 1 - e^-4 (about 98%).
+type CountMin struct {
+	counts [cmDepth][]uint64
+	total  uint64
+}
+
+func NewCountMin() *CountMin {
+	c := &CountMin{}
+	for i := range c.counts {
+		c.counts[i] = make([]uint64, cmWidth)
+	}
+	return c
+}
+
+// index derives one column for each row from a single hash (Kirsch-Mitzenmacher).
+func (c *CountMin) index(hash uint64, row int) int {
+	h1, h2 := uint32(hash), uint32(hash>>32)|1
+	return int((h1 + uint32(row)*h2) % cmWidth)
+}
+
+// Add adds value to key with given hash.
+func (c *CountMin) Add(hash uint64, value uint64) {
+	for row := range c.counts {
+		c.counts[row][c.index(hash, row)] += value
+	}
+	c.total += value
+}
+
+// Estimate returns estimated sum of key with given hash.
+func (c *CountMin) Estimate(hash uint64) uint64 {
+	ret := uint64(math.MaxUint64)
+	for row := range c.counts {
+		ret = min(ret, c.counts[row][c.index(hash, row)])
+	}
+	return ret
+}
+
+// Total returns sum of all values added.
+func (c *CountMin) Total() uint64 {
+	return c.total
+}
+
+// ErrorBound returns how much an estimate might exceed the true sum.
+func (c *CountMin) ErrorBound() uint64 {
+	return uint64(math.Ceil(math.E * float64(c.total) / cmWidth))
+}
+
+// Merge adds all counts of other to c.
+func (c *CountMin) Merge(other *CountMin) {
+	for row := range c.counts {
+		for i, v := range other.counts[row] {
+			c.counts[row][i] += v
+		}
+	}
+	c.total += other.total
+}
+
+func (c *CountMin) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, 8*(cmDepth*cmWidth+1))
+	data = binary.LittleEndian.AppendUint64(data, c.total)
+	for row := range c.counts {
+		for _, v := range c.counts[row] {
+			data = binary.LittleEndian.AppendUint64(data, v)
+		}
+	}
+	return data, nil
+}
+
+func (c *CountMin) UnmarshalBinary(data []byte) error {
+	if len(data) != 8*(cmDepth*cmWidth+1) {
+		return errors.New("invalid CountMin size")
+	}
+	c.total = binary.LittleEndian.Uint64(data)
+	data = data[8:]
+	for row := range c.counts {
+		c.counts[row] = make([]uint64, cmWidth)
+		for i := range c.counts[row] {
+			c.counts[row][i] = binary.LittleEndian.Uint64(data[8*i:])
+		}
+		data = data[8*cmWidth:]
+	}
+	return nil
+}
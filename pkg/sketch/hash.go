@@ -0,0 +1,49 @@
+// Package sketch provides fixed-size probabilistic summaries for counting
+// over a large number of distinct keys.
+package sketch
+
+// Hashes are also stored in snapshots, so they must not depend on process
+// (unlike hash/maphash).
+
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+// Hasher computes 64-bit FNV-1a hash of written data, mixed for better distribution of low bits.
+type Hasher uint64
+
+func NewHasher() Hasher {
+	return fnvOffset
+}
+
+func (h *Hasher) Write(b []byte) {
+	for _, c := range b {
+		*h ^= Hasher(c)
+		*h *= fnvPrime
+	}
+}
+
+func (h *Hasher) WriteString(s string) {
+	for i := 0; i < len(s); i++ {
+		*h ^= Hasher(s[i])
+		*h *= fnvPrime
+	}
+}
+
+// Sum64 returns the hash, finalized with splitmix64.
+func (h Hasher) Sum64() uint64 {
+	x := uint64(h)
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+func HashString(s string) uint64 {
+	h := NewHasher()
+	h.WriteString(s)
+	return h.Sum64()
+}
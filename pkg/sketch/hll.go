@@ -0,0 +1,70 @@
+package sketch
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// 2^hllPrecision registers, 1 byte each
+const hllPrecision = 8
+
+const hllRegisters = 1 << hllPrecision
+
+// HyperLogLog estimates number of distinct items with fixed memory.
+type HyperLogLog struct {
+	registers [hllRegisters]uint8
+}
+
+// Add adds an item by its hash.
+func (h *HyperLogLog) Add(hash uint64) {
+	idx := hash >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1))) + 1
+	h.registers[idx] = max(h.registers[idx], rank)
+}
+
+func (h *HyperLogLog) AddString(s string) {
+	h.Add(HashString(s))
+}
+
+// Merge makes h estimate the union of h and other.
+func (h *HyperLogLog) Merge(other *HyperLogLog) {
+	for i, r := range other.registers {
+		h.registers[i] = max(h.registers[i], r)
+	}
+}
+
+// Estimate returns estimated number of distinct items added.
+func (h *HyperLogLog) Estimate() uint64 {
+	const m = float64(hllRegisters)
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	alpha := 0.7213 / (1 + 1.079/m)
+	e := alpha * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		// Linear counting is more accurate for small cardinalities
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(math.Round(e))
+}
+
+// HLLRelativeError is the standard error of HyperLogLog estimates.
+var HLLRelativeError = 1.04 / math.Sqrt(hllRegisters)
+
+func (h *HyperLogLog) MarshalBinary() ([]byte, error) {
+	return h.registers[:], nil
+}
+
+func (h *HyperLogLog) UnmarshalBinary(data []byte) error {
+	if len(data) != hllRegisters {
+		return errors.New("invalid HyperLogLog size")
+	}
+	copy(h.registers[:], data)
+	return nil
+}
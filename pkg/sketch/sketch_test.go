@@ -0,0 +1,76 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	as := assert.New(t)
+	var h HyperLogLog
+	as.Equal(uint64(0), h.Estimate())
+	for range 3 {
+		h.AddString("apt")
+	}
+	as.Equal(uint64(1), h.Estimate())
+
+	for _, n := range []int{100, 10000, 1000000} {
+		var h, h1, h2 HyperLogLog
+		for i := range n {
+			s := fmt.Sprintf("item%d", i)
+			h.AddString(s)
+			if i%2 == 0 {
+				h1.AddString(s)
+			} else {
+				h2.AddString(s)
+			}
+		}
+		// 4 times of standard error
+		as.InDelta(n, h.Estimate(), 4*HLLRelativeError*float64(n), "n = %d", n)
+		h1.Merge(&h2)
+		as.Equal(h.Estimate(), h1.Estimate())
+	}
+
+	data, err := h.MarshalBinary()
+	as.NoError(err)
+	var h3 HyperLogLog
+	as.NoError(h3.UnmarshalBinary(data))
+	as.Equal(h, h3)
+	as.Error(h3.UnmarshalBinary(data[1:]))
+}
+
+func TestCountMin(t *testing.T) {
+	as := assert.New(t)
+	c := NewCountMin()
+	c2 := NewCountMin()
+	truth := make(map[uint64]uint64)
+	for i := range 100000 {
+		h := HashString(fmt.Sprintf("key%d", i%30000))
+		v := uint64(i%100 + 1)
+		truth[h] += v
+		if i%2 == 0 {
+			c.Add(h, v)
+		} else {
+			c2.Add(h, v)
+		}
+	}
+	c.Merge(c2)
+	exceeded := 0
+	for h, v := range truth {
+		e := c.Estimate(h)
+		as.GreaterOrEqual(e, v)
+		if e-v > c.ErrorBound() {
+			exceeded++
+		}
+	}
+	as.Less(float64(exceeded), math.Exp(-cmDepth)*float64(len(truth)))
+
+	data, err := c.MarshalBinary()
+	as.NoError(err)
+	c3 := &CountMin{}
+	as.NoError(c3.UnmarshalBinary(data))
+	as.Equal(c, c3)
+}
@@ -0,0 +1,160 @@
+// Package timeseek skips parts of time-ordered log files outside a time range,
+// without parsing every line.
+package timeseek
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/taoky/ayano/pkg/fileiter"
+	"github.com/taoky/ayano/pkg/parser"
+	"github.com/taoky/ayano/pkg/util"
+)
+
+// Log lines are not strictly ordered by time, for example nginx writes
+// $time_local when a request finishes, so boundaries are relaxed by Slack.
+const Slack = 5 * time.Minute
+
+// Stop binary search when range is smaller than this, and scan linearly instead
+const minSeekRange = 64 * 1024
+
+// Number of lines to look for a timestamp at a position
+const probeLines = 16
+
+// Until checks one line in every checkInterval lines
+const checkInterval = 1024
+
+// Supported reports whether p could parse lines from the middle of a file.
+func Supported(p parser.Parser) bool {
+	return !parser.IsStateful(p)
+}
+
+// Seek finds the offset of a line at or before the first line not earlier than from - Slack,
+// with binary search in [start, end) of r. start must be at beginning of a line.
+func Seek(r io.ReaderAt, start, end int64, p parser.Parser, from time.Time) (int64, error) {
+	threshold := from.Add(-Slack)
+	lo, hi := start, end
+	for hi-lo > minSeekRange {
+		mid := lo + (hi-lo)/2
+		lineStart, t, err := probe(r, mid, hi, p)
+		if err != nil {
+			return 0, err
+		}
+		if !t.IsZero() && t.Before(threshold) {
+			lo = lineStart
+		} else {
+			// Either no timestamp in [mid, hi), or answer is before lineStart
+			hi = mid
+		}
+	}
+	return lo, nil
+}
+
+// probe returns the first line starting in [pos, end) which has a timestamp.
+// Zero time is returned if not found.
+func probe(r io.ReaderAt, pos, end int64, p parser.Parser) (int64, time.Time, error) {
+	br := bufio.NewReader(io.NewSectionReader(r, pos, end-pos))
+	offset := pos
+	readLine := func() ([]byte, error) {
+		line, err := br.ReadBytes('\n')
+		offset += int64(len(line))
+		return line, err
+	}
+	// Skip the partial line at pos
+	if _, err := readLine(); err != nil {
+		return 0, time.Time{}, ignoreEOF(err)
+	}
+	for range probeLines {
+		lineStart := offset
+		line, err := readLine()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, time.Time{}, err
+		}
+		if err != nil {
+			// Incomplete line at the end of range
+			return 0, time.Time{}, nil
+		}
+		item, perr := p.Parse(bytes.TrimRight(line, "\r\n"))
+		if perr == nil && !item.Discard && !item.Time.IsZero() {
+			return lineStart, item.Time, nil
+		}
+	}
+	return 0, time.Time{}, nil
+}
+
+func ignoreEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// OpenFrom opens a log file with openFile, but if it's a plain file, skips to a line near from.
+// Zero from means no seeking.
+func OpenFrom(filename string, openFile func(string) (io.ReadCloser, error), p parser.Parser, from time.Time) (io.ReadCloser, error) {
+	if from.IsZero() || !Supported(p) || util.IsStream(filename) {
+		return openFile(filename)
+	}
+	compressed, err := util.IsCompressed(filename)
+	if err != nil {
+		return nil, err
+	}
+	if compressed {
+		return openFile(filename)
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	fileInfo, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	offset, err := Seek(f, 0, fileInfo.Size(), p, from)
+	if err == nil {
+		_, err = f.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+type untilIterator struct {
+	iter  fileiter.Iterator
+	p     parser.Parser
+	limit time.Time
+	count int
+}
+
+// Until wraps iter, ending it when a line is later than to + Slack.
+// Only a few lines are parsed for checking, so some lines after that might still be returned.
+// Zero to means no limit.
+func Until(iter fileiter.Iterator, p parser.Parser, to time.Time) fileiter.Iterator {
+	if to.IsZero() || !Supported(p) {
+		return iter
+	}
+	return &untilIterator{iter: iter, p: p, limit: to.Add(Slack)}
+}
+
+func (u *untilIterator) Next() ([]byte, error) {
+	line, err := u.iter.Next()
+	if line == nil || err != nil {
+		return line, err
+	}
+	if u.count%checkInterval == 0 {
+		item, err := u.p.Parse(line)
+		if err == nil && !item.Discard && item.Time.After(u.limit) {
+			return nil, nil
+		}
+	}
+	u.count++
+	return line, nil
+}
@@ -0,0 +1,82 @@
+package timeseek
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taoky/ayano/pkg/fileiter"
+	"github.com/taoky/ayano/pkg/parser"
+)
+
+var baseTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// One line per second, with a garbage line every 100 lines
+func testLog(lines int) (string, []int64) {
+	var sb strings.Builder
+	var offsets []int64
+	for i := range lines {
+		if i%100 == 50 {
+			sb.WriteString("garbage\n")
+		}
+		offsets = append(offsets, int64(sb.Len()))
+		t := baseTime.Add(time.Duration(i) * time.Second)
+		fmt.Fprintf(&sb, "10.0.0.1 - - [%s] \"GET /%d HTTP/1.1\" 200 100 \"-\" \"ua\"\n", t.Format("02/Jan/2006:15:04:05 -0700"), i)
+	}
+	return sb.String(), offsets
+}
+
+func TestSeek(t *testing.T) {
+	as := assert.New(t)
+	p, err := parser.GetParser("nginx-combined")
+	as.NoError(err)
+	content, offsets := testLog(20000)
+	r := strings.NewReader(content)
+	size := int64(len(content))
+
+	for _, sec := range []int{0, 100, 5000, 12345, 19999, 30000} {
+		from := baseTime.Add(time.Duration(sec) * time.Second)
+		offset, err := Seek(r, 0, size, p, from)
+		as.NoError(err)
+		// Never skips lines in range
+		idx := min(sec, len(offsets)-1)
+		as.LessOrEqual(offset, offsets[idx], sec)
+		if offset > 0 {
+			as.Equal(byte('\n'), content[offset-1])
+		}
+		// Lines skipped are outside slack
+		lower := min(max(0, sec-int(Slack/time.Second))-1, len(offsets)-1)
+		if lower > 0 {
+			as.GreaterOrEqual(offset, offsets[lower]-2*minSeekRange, sec)
+		}
+	}
+	offset, err := Seek(r, 0, size, p, baseTime.Add(-time.Hour))
+	as.NoError(err)
+	as.Equal(int64(0), offset)
+}
+
+func TestUntil(t *testing.T) {
+	as := assert.New(t)
+	p, err := parser.GetParser("nginx-combined")
+	as.NoError(err)
+	content, _ := testLog(10000)
+	to := baseTime.Add(3000 * time.Second)
+	iter := Until(fileiter.NewWithScanner(strings.NewReader(content)), p, to)
+	var last parser.LogItem
+	count := 0
+	for {
+		line, err := iter.Next()
+		as.NoError(err)
+		if line == nil {
+			break
+		}
+		count++
+		if item, err := p.Parse(line); err == nil && !item.Discard {
+			last = item
+		}
+	}
+	as.False(last.Time.Before(to.Add(Slack)))
+	as.Less(count, 3000+int(Slack/time.Second)+2*checkInterval)
+}
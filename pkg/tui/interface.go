@@ -71,10 +71,14 @@ func (t *Tui) handleInput(key byte) {
 	case 's':
 		t.handles()
 	case 'S':
-		if t.sortBy == analyze.SortBySize {
+		switch t.sortBy {
+		case analyze.SortBySize:
 			t.sortBy = analyze.SortByRequests
 			fmt.Println("Switched to sort by requests")
-		} else {
+		case analyze.SortByRequests:
+			t.sortBy = analyze.SortByRate
+			fmt.Println("Switched to sort by rate")
+		default:
 			t.sortBy = analyze.SortBySize
 			fmt.Println("Switched to sort by size")
 		}
@@ -1,11 +1,16 @@
 package util
 
 import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
 	"errors"
 	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
 )
 
 const oneMiB = 1024 * 1024
@@ -20,7 +25,8 @@ func (fr *filteredReader) Read(p []byte) (n int, err error) {
 }
 
 func (fr *filteredReader) Close() error {
-	return errors.Join(fr.cmd.Wait(), fr.r.Close())
+	// Close pipe first, or Wait would block forever if the command is still writing
+	return errors.Join(fr.r.Close(), fr.cmd.Wait())
 }
 
 func filterByCommand(r io.Reader, args []string) (io.ReadCloser, error) {
@@ -36,27 +42,136 @@ func filterByCommand(r io.Reader, args []string) (io.ReadCloser, error) {
 	return &filteredReader{cmd: cmd, r: stdout}, nil
 }
 
-type filterFunc func(r io.ReadCloser) (io.ReadCloser, error)
+// multiCloser reads from r, and closes all closers in order
+type multiCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m *multiCloser) Close() error {
+	var errs []error
+	for _, c := range m.closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+type compression struct {
+	magic   []byte
+	command []string
+	reader  func(r io.Reader) (io.ReadCloser, error)
+}
 
-var fileTypes = map[string]filterFunc{
-	".gz": func(r io.ReadCloser) (io.ReadCloser, error) {
-		return filterByCommand(r, []string{"gzip", "-cd"})
+var compressions = []compression{
+	{
+		magic:   []byte{0x1f, 0x8b},
+		command: []string{"gzip", "-cd"},
+		reader: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	},
+	{
+		magic:   []byte("BZh"),
+		command: []string{"bzip2", "-cd"},
+		reader: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(bzip2.NewReader(r)), nil
+		},
 	},
-	".xz": func(r io.ReadCloser) (io.ReadCloser, error) {
-		return filterByCommand(r, []string{"xz", "-cd", "-T", "0"})
+	{
+		magic:   []byte{0xfd, '7', 'z', 'X', 'Z', 0x00},
+		command: []string{"xz", "-cd", "-T", "0"},
+		reader: func(r io.Reader) (io.ReadCloser, error) {
+			xr, err := xz.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return io.NopCloser(xr), nil
+		},
 	},
-	".zst": func(r io.ReadCloser) (io.ReadCloser, error) {
-		return filterByCommand(r, []string{"zstd", "-cd", "-T0"})
+	{
+		magic:   []byte{0x28, 0xb5, 0x2f, 0xfd},
+		command: []string{"zstd", "-cd", "-T0"},
+		reader: func(r io.Reader) (io.ReadCloser, error) {
+			zr, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return zr.IOReadCloser(), nil
+		},
 	},
 }
 
+const maxMagicLen = 6
+
+// OpenFile opens a log file, decompressing it in-process if it's compressed.
+// Compression format is detected by magic bytes rather than file extension.
+// "-" means stdin, and named pipes are also supported.
 func OpenFile(filename string) (io.ReadCloser, error) {
+	return openFile(filename, false)
+}
+
+// OpenFileWithCommand is like OpenFile, but decompresses with external commands (gzip, xz, etc.),
+// which might be faster.
+func OpenFileWithCommand(filename string) (io.ReadCloser, error) {
+	return openFile(filename, true)
+}
+
+// IsCompressed reports whether a file is compressed, by its magic bytes.
+func IsCompressed(filename string) (bool, error) {
 	f, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	magic := make([]byte, maxMagicLen)
+	n, err := io.ReadFull(f, magic)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	for _, c := range compressions {
+		if bytes.HasPrefix(magic[:n], c.magic) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func openFile(filename string, useCommand bool) (io.ReadCloser, error) {
+	var f io.ReadCloser
+	var err error
+	if IsStream(filename) {
+		f, err = openStream(filename)
+	} else {
+		f, err = os.Open(filename)
+	}
 	if err != nil {
 		return nil, err
 	}
-	if filter, ok := fileTypes[filepath.Ext(filename)]; ok {
-		return filter(f)
+	magic := make([]byte, maxMagicLen)
+	n, err := io.ReadFull(f, magic)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		f.Close()
+		return nil, err
+	}
+	magic = magic[:n]
+	// Put magic bytes back, without requiring f to be seekable
+	r := io.MultiReader(bytes.NewReader(magic), f)
+
+	for _, c := range compressions {
+		if !bytes.HasPrefix(magic, c.magic) {
+			continue
+		}
+		var dr io.ReadCloser
+		if useCommand {
+			dr, err = filterByCommand(r, c.command)
+		} else {
+			dr, err = c.reader(r)
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &multiCloser{Reader: dr, closers: []io.Closer{dr, f}}, nil
 	}
-	return f, nil
+	return &multiCloser{Reader: r, closers: []io.Closer{f}}, nil
 }
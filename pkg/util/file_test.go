@@ -0,0 +1,89 @@
+package util
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/ulikunitz/xz"
+)
+
+const testContent = "hello\nworld\n"
+
+// bzip2 of testContent, as compress/bzip2 has no writer
+var testBzip2 = []byte{66, 90, 104, 57, 49, 65, 89, 38, 83, 89, 107, 95, 177, 221, 0, 0, 2, 65, 128, 0, 16, 6, 68, 144, 128, 32, 0, 49, 12, 8, 33, 163, 105, 8, 7, 35, 174, 135, 139, 185, 34, 156, 40, 72, 53, 175, 216, 238, 128}
+
+func compressWith(t *testing.T, newWriter func(w io.Writer) (io.WriteCloser, error)) []byte {
+	var buf bytes.Buffer
+	w, err := newWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(testContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOpenFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"plain.log": []byte(testContent),
+		"short.log": []byte("a"),
+		"gzip.log.gz": compressWith(t, func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		}),
+		"xz.log.xz": compressWith(t, func(w io.Writer) (io.WriteCloser, error) {
+			return xz.NewWriter(w)
+		}),
+		"zstd.log.zst": compressWith(t, func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w)
+		}),
+		"bzip2.log.bz2": testBzip2,
+		// Detected by content rather than extension
+		"gzip-without-ext.log": compressWith(t, func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		}),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	openFuncs := map[string]func(string) (io.ReadCloser, error){
+		"in-process": OpenFile,
+	}
+	if _, err := exec.LookPath("gzip"); err == nil {
+		openFuncs["command"] = OpenFileWithCommand
+	}
+	for funcName, openFile := range openFuncs {
+		for name := range files {
+			if funcName == "command" && name != "gzip.log.gz" && name != "plain.log" {
+				// other commands might be missing
+				continue
+			}
+			expected := testContent
+			if name == "short.log" {
+				expected = "a"
+			}
+			f, err := openFile(filepath.Join(dir, name))
+			if !assert.NoError(t, err, name) {
+				continue
+			}
+			content, err := io.ReadAll(f)
+			assert.NoError(t, err, name)
+			assert.Equal(t, expected, string(content), "%s (%s)", name, funcName)
+			assert.NoError(t, f.Close(), name)
+		}
+	}
+}
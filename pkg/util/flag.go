@@ -31,3 +31,8 @@ func (s *SizeFlag) Set(value string) error {
 func (s SizeFlag) Type() string {
 	return "size"
 }
+
+// UnmarshalText allows sizes like "200GiB" in config files.
+func (s *SizeFlag) UnmarshalText(text []byte) error {
+	return s.Set(string(text))
+}
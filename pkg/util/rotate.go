@@ -0,0 +1,131 @@
+package util
+
+import (
+	"cmp"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var CompressionSuffixes = []string{".gz", ".bz2", ".xz", ".zst"}
+
+var (
+	// access.log.1, access.log.2.gz
+	rotatedNumRe = regexp.MustCompile(`^(.+)\.(\d+)$`)
+	// logrotate's dateext: access.log-20240101, access.log-2024010112, access.log.2024-01-01
+	rotatedDateRe = regexp.MustCompile(`^(.+)[-.](\d{8}|\d{10}|\d{4}-\d{2}-\d{2})$`)
+)
+
+type rotatedName struct {
+	filename string
+	base     string
+	// 0 for the base file itself
+	num  int
+	date string
+}
+
+func parseRotatedName(filename string) rotatedName {
+	name := filename
+	for _, ext := range CompressionSuffixes {
+		if trimmed, ok := strings.CutSuffix(name, ext); ok {
+			name = trimmed
+			break
+		}
+	}
+	if m := rotatedDateRe.FindStringSubmatch(name); m != nil {
+		return rotatedName{filename: filename, base: m[1], date: strings.ReplaceAll(m[2], "-", "")}
+	}
+	if m := rotatedNumRe.FindStringSubmatch(name); m != nil {
+		num, err := strconv.Atoi(m[2])
+		if err == nil {
+			return rotatedName{filename: filename, base: m[1], num: num}
+		}
+	}
+	return rotatedName{filename: filename, base: name}
+}
+
+// compareAge returns negative if a is older than b
+func (a rotatedName) compareAge(b rotatedName) int {
+	aIsBase := a.num == 0 && a.date == ""
+	bIsBase := b.num == 0 && b.date == ""
+	switch {
+	case aIsBase != bIsBase:
+		// base file is the newest
+		if aIsBase {
+			return 1
+		}
+		return -1
+	case a.date != "" && b.date != "":
+		return strings.Compare(a.date, b.date)
+	case a.date != "" || b.date != "":
+		// mixed styles, date first
+		if a.date != "" {
+			return -1
+		}
+		return 1
+	default:
+		// larger number is older
+		return cmp.Compare(b.num, a.num)
+	}
+}
+
+// SortRotated orders files in the same logrotate set oldest-first,
+// like access.log.2.gz, access.log.1, access.log.
+// Files of different sets keep the order of first appearance.
+func SortRotated(filenames []string) []string {
+	var bases []string
+	groups := make(map[string][]rotatedName)
+	for _, filename := range filenames {
+		if IsStream(filename) {
+			// keep as a group by itself
+			groups[filename] = []rotatedName{{filename: filename}}
+			bases = append(bases, filename)
+			continue
+		}
+		r := parseRotatedName(filename)
+		if _, ok := groups[r.base]; !ok {
+			bases = append(bases, r.base)
+		}
+		groups[r.base] = append(groups[r.base], r)
+	}
+
+	result := make([]string, 0, len(filenames))
+	for _, base := range bases {
+		group := groups[base]
+		slices.SortStableFunc(group, rotatedName.compareAge)
+		for _, r := range group {
+			result = append(result, r.filename)
+		}
+	}
+	return result
+}
+
+// RotatedSiblings finds all existing files in the logrotate set of filename
+// (which could be either the base file or a rotated one), oldest first.
+func RotatedSiblings(filename string) ([]string, error) {
+	base := parseRotatedName(filename).base
+	matches, err := filepath.Glob(escapeGlob(base) + "*")
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, m := range matches {
+		if parseRotatedName(m).base == base {
+			result = append(result, m)
+		}
+	}
+	return SortRotated(result), nil
+}
+
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[\`, c) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortRotated(t *testing.T) {
+	as := assert.New(t)
+	as.Equal([]string{
+		"access.log.10.gz", "access.log.2.gz", "access.log.1", "access.log",
+		"error.log.1", "error.log",
+	}, SortRotated([]string{
+		"access.log", "access.log.1", "access.log.10.gz", "access.log.2.gz",
+		"error.log", "error.log.1",
+	}))
+	as.Equal([]string{
+		"access.log-20240101.gz", "access.log-20240102.zst", "access.log-20240103", "access.log",
+	}, SortRotated([]string{
+		"access.log", "access.log-20240103", "access.log-20240101.gz", "access.log-20240102.zst",
+	}))
+	as.Equal([]string{"b.log", "a.log"}, SortRotated([]string{"b.log", "a.log"}))
+}
+
+func TestRotatedSiblings(t *testing.T) {
+	as := assert.New(t)
+	dir := t.TempDir()
+	for _, name := range []string{
+		"access.log", "access.log.1", "access.log.2.gz", "access.log.old",
+		"access.log-backup", "access.logs", "access.log-20240101.gz",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{
+		filepath.Join(dir, "access.log-20240101.gz"),
+		filepath.Join(dir, "access.log.2.gz"),
+		filepath.Join(dir, "access.log.1"),
+		filepath.Join(dir, "access.log"),
+	}
+	siblings, err := RotatedSiblings(filepath.Join(dir, "access.log"))
+	as.NoError(err)
+	as.Equal(expected, siblings)
+	siblings, err = RotatedSiblings(filepath.Join(dir, "access.log.1"))
+	as.NoError(err)
+	as.Equal(expected, siblings)
+}
@@ -0,0 +1,100 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// StdinFilename is the filename meaning standard input.
+const StdinFilename = "-"
+
+// Streams (stdin and named pipes) could only be read once, and writers would get EPIPE
+// if we close them. So they're opened only once and kept open, with data read ahead
+// (e.g. for parser detection) put back to be read again.
+type stream struct {
+	f      *os.File
+	prefix []byte
+}
+
+var (
+	streamsMu sync.Mutex
+	streams   = make(map[string]*stream)
+)
+
+// IsStream reports whether filename is stdin or a named pipe.
+func IsStream(filename string) bool {
+	if filename == StdinFilename {
+		return true
+	}
+	fi, err := os.Stat(filename)
+	return err == nil && fi.Mode()&os.ModeNamedPipe != 0
+}
+
+func getStream(filename string) (*stream, error) {
+	if s, ok := streams[filename]; ok {
+		return s, nil
+	}
+	var f *os.File
+	if filename == StdinFilename {
+		f = os.Stdin
+	} else {
+		var err error
+		f, err = os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s := &stream{f: f}
+	streams[filename] = s
+	return s, nil
+}
+
+// openStream returns a reader of stream, beginning with data put back.
+// Closing it does not close the underlying stream.
+func openStream(filename string) (io.ReadCloser, error) {
+	streamsMu.Lock()
+	defer streamsMu.Unlock()
+	s, err := getStream(filename)
+	if err != nil {
+		return nil, err
+	}
+	r := io.MultiReader(bytes.NewReader(s.prefix), s.f)
+	s.prefix = nil
+	return io.NopCloser(r), nil
+}
+
+// PeekStreamLines reads at most n lines from a stream, and puts them back.
+// It blocks until n lines are available or the stream ends.
+func PeekStreamLines(filename string, n int) ([][]byte, error) {
+	streamsMu.Lock()
+	defer streamsMu.Unlock()
+	s, err := getStream(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(io.MultiReader(bytes.NewReader(s.prefix), s.f))
+	var consumed []byte
+	var lines [][]byte
+	for len(lines) < n {
+		line, err := br.ReadBytes('\n')
+		consumed = append(consumed, line...)
+		if len(line) > 0 {
+			lines = append(lines, bytes.TrimRight(line, "\r\n"))
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			s.prefix = consumed
+			return lines, err
+		}
+	}
+	buffered, _ := br.Peek(br.Buffered())
+	s.prefix = append(consumed, buffered...)
+	return lines, nil
+}